@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+// objectRequirement is the action on the object that required by middleware,
+// the object ID is extracted from the path param of matched permission route
+type objectRequirement struct {
+	action       string
+	resourceType string
+	param        string
+}
+
+// RequireObjectAction will reject the request if user can't do the action on the object,
+// the object ID is the path param of matched permission route, e.g. param `id` of route `/dashboards/{id}`.
+// the path params are only resolved by WithRBAC or WithRules, the request without the path param is rejected.
+// the object grants aren't in the api key scope, so the request that authenticated by api key is rejected
+func RequireObjectAction(action, resourceType, param string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.objects = append(opts.objects, objectRequirement{
+			action:       action,
+			resourceType: resourceType,
+			param:        param,
+		})
+	}
+}
+
+// CanOnObject will check the user can do the action on the object (resource type and resource ID)
+// the action can be granted to the user directly, or through the roles and groups of user by schema.ResourceACL
+func (a *Auth) CanOnObject(ctx context.Context, user *schema.User, action, resourceType, id string) (bool, error) {
+	if user == nil {
+		return false, ErrInvalidUserLogin
+	}
+	return a.dbSchema.User(user).CanOnObjectContext(ctx, action, resourceType, id)
+}
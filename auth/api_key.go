@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrInvalidAPIKey    = errors.New("invalid api key")
+	ErrAPIKeyExpired    = errors.New("api key is expired")
+	ErrAPIKeyNotFound   = errors.New("api key not found")
+	ErrAPIKeyPermission = errors.New("api key permission is not owned by user")
+)
+
+const (
+	apiKeyScheme    = "ApiKey"
+	apiKeyPrefix    = "gk_"
+	apiKeyIDSize    = 6
+	apiKeyLastUsage = time.Minute
+)
+
+// generateAPIKey will generate api key with format `gk_<prefix>_<secret>`
+// the prefix is used to find the key, and the whole key is stored as hash
+func generateAPIKey() (string, string, error) {
+	id := make([]byte, apiKeyIDSize)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(id)
+	return apiKeyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+// parseAPIKey will return the prefix of api key
+func parseAPIKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyPrefix), "_", 2)
+	if len(parts) != 2 || len(parts[0]) != apiKeyIDSize*2 || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// CreateAPIKey will create a new api key for user, the api key can only access the permissions in its scope
+// every permission must be owned by user, and expiredAt can be nil for api key without expiration.
+// It'll return the plain api key, the key is only stored as hash so it must be shown to user at this time
+func (a *Auth) CreateAPIKey(user *schema.User, name string, permissions []schema.Permission, expiredAt *time.Time) (string, *schema.APIKey, error) {
+	userPermissions, err := a.dbSchema.User(user).GetPermissions()
+	if err != nil {
+		return "", nil, err
+	}
+	owned := make(map[int64]bool)
+	for _, permission := range userPermissions {
+		owned[permission.ID] = true
+	}
+	for _, permission := range permissions {
+		if !owned[permission.ID] {
+			return "", nil, ErrAPIKeyPermission
+		}
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	apiKey := a.dbSchema.APIKey(&schema.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		ExpiredAt: expiredAt,
+	})
+	err = apiKey.CreateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i := range permissions {
+		err = apiKey.AddPermission(&permissions[i])
+		if err != nil {
+			apiKey.Delete()
+			return "", nil, err
+		}
+	}
+	return key, apiKey, nil
+}
+
+// ListAPIKeys will return all api keys of user
+func (a *Auth) ListAPIKeys(userID int64) ([]schema.APIKey, error) {
+	return a.dbSchema.APIKey(nil).GetUserAPIKeys(userID)
+}
+
+// RevokeAPIKey will delete the api key of user, the api key can't be used anymore
+func (a *Auth) RevokeAPIKey(userID, apiKeyID int64) error {
+	apiKey, err := a.dbSchema.APIKey(nil).FindAPIKey(apiKeyID)
+	if err != nil {
+		return err
+	}
+	if apiKey == nil || apiKey.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	return apiKey.Delete()
+}
+
+// VerifyAPIKey will validate the api key and return the owner of api key
+// the last used time of api key will be updated
+func (a *Auth) VerifyAPIKey(key string) (*schema.User, *schema.APIKey, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := a.dbSchema.APIKey(nil).FindAPIKeyByPrefix(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashToken(key))) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if apiKey.IsExpired() {
+		return nil, nil, ErrAPIKeyExpired
+	}
+
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": apiKey.UserID,
+	})
+	if err != nil || user == nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if !user.Active {
+		return nil, nil, ErrUserNotActive
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsage {
+		err = apiKey.UpdateLastUsed(now)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, apiKey, nil
+}
+
+// GetAPIKey is helper function to get the api key that used to authenticate the request
+// You should using middleware authentication before call this function
+// If the request isn't authenticated by api key, it'll return nil
+func GetAPIKey(r *http.Request) *schema.APIKey {
+	apiKey, ok := r.Context().Value(APIKeyPrinciple).(*schema.APIKey)
+	if !ok {
+		return nil
+	}
+	return apiKey
+}
@@ -3,16 +3,17 @@ package auth
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
 
+	"github.com/dhanarJkusuma/guardian/auth/oidc"
 	"github.com/dhanarJkusuma/guardian/auth/password"
+	"github.com/dhanarJkusuma/guardian/auth/session"
 	"github.com/dhanarJkusuma/guardian/auth/token"
+	"github.com/dhanarJkusuma/guardian/event"
 	"github.com/dhanarJkusuma/guardian/schema"
 )
 
@@ -31,6 +32,10 @@ var (
 type LoginParams struct {
 	Identifier string
 	Password   string
+
+	// IPAddress and UserAgent are stored as session metadata
+	IPAddress string
+	UserAgent string
 }
 
 type LoginMethod int
@@ -45,15 +50,76 @@ const (
 
 	authorization string = "Authorization"
 	UserPrinciple string = "UserPrinciple"
+	PathParams    string = "PathParams"
+	SessionID     string = "SessionID"
+	CSRFToken     string = "CSRFToken"
+
+	ImpersonatorPrinciple string = "ImpersonatorPrinciple"
+
+	APIKeyPrinciple string = "APIKeyPrinciple"
+
+	Domain string = "Domain"
 )
 
 type Options struct {
 	SessionName  string
 	GuardSchema  *schema.Schema
-	CacheClient  *redis.Client
+	SessionStore session.SessionStore
 	LoginMethod  LoginMethod
 	ExpiredInSec int64
 
+	// RefreshExpiredInSec is lifetime of refresh token, refresh token is disabled if the value <= 0
+	RefreshExpiredInSec int64
+
+	// JWTStrategy is used to issue stateless jwt token instead of token that stored in the session store
+	// the jwt token is expired after 24 hours if ExpiredInSec <= 0
+	// JWTDenylist will store the revoked jwt token id in the session store until the token is expired
+	JWTStrategy *token.JWTStrategy
+	JWTDenylist bool
+
+	// Notifier is used to deliver the password reset token and email verification token to user
+	// PasswordResetExpiredInSec is lifetime of password reset token, default is 1 hour
+	Notifier                  Notifier
+	PasswordResetExpiredInSec int64
+
+	// EmailVerification will send email verification token when user is registered by Register
+	// RequireVerifiedEmail will reject the login of user that hasn't verified the email with ErrEmailNotVerified
+	// EmailVerificationExpiredInSec is lifetime of email verification token, default is 24 hours
+	EmailVerification             bool
+	RequireVerifiedEmail          bool
+	EmailVerificationExpiredInSec int64
+
+	// MFAIssuer is the issuer name that shown in the authenticator app
+	MFAIssuer string
+
+	// OIDCProviders is the OpenID Connect providers that can be used by BeginOIDCLogin
+	// OIDCAutoRegister will create a new user when the external identity isn't linked to any user
+	// OIDCLinkVerifiedEmail will link the external identity to the user with the same email if the email is verified by provider
+	OIDCProviders         []*oidc.Provider
+	OIDCAutoRegister      bool
+	OIDCLinkVerifiedEmail bool
+
+	// Throttle is used to protect Authenticate from brute-force attack
+	Throttle ThrottleOptions
+
+	// PasswordPolicy is used to validate the new password, prevent password reuse, and expire the old password
+	PasswordPolicy *password.Policy
+
+	// ImpersonatePermission is the permission name that required to impersonate other user, default is `impersonate_user`
+	ImpersonatePermission string
+
+	// CSRF is used to protect cookie based authentication from cross-site request forgery
+	CSRF CSRFOptions
+
+	// Cookie contains the attributes of session cookie
+	Cookie CookieOptions
+
+	// ErrorHandler will write the response when the middleware rejects the request, default is DefaultErrorHandler
+	ErrorHandler ErrorHandler
+
+	// CacheClient is only used when SessionStore is not provided
+	CacheClient *redis.Client
+
 	TokenStrategy    token.TokenGenerator
 	PasswordStrategy password.PasswordGenerator
 }
@@ -61,33 +127,107 @@ type Options struct {
 // Auth is an entity that has responsibility to handle authentication in the guardian library
 type Auth struct {
 	sessionName      string
-	cacheClient      *redis.Client
+	sessionStore     session.SessionStore
 	loginMethod      LoginMethod
 	expiredInSeconds int64
+	refreshInSeconds int64
 
 	tokenStrategy    token.TokenGenerator
 	passwordStrategy password.PasswordGenerator
+	passwordPolicy   *password.Policy
+	jwtStrategy      *token.JWTStrategy
+	jwtDenylist      bool
+	throttle         ThrottleOptions
+	csrf             CSRFOptions
+	cookie           CookieOptions
+	errorHandler     ErrorHandler
+	mfaIssuer        string
+
+	notifier               Notifier
+	passwordResetInSeconds int64
+
+	impersonatePermissionName string
+
+	emailVerification          bool
+	requireVerifiedEmail       bool
+	emailVerificationInSeconds int64
+
+	oidcProviders         map[string]*oidc.Provider
+	oidcAutoRegister      bool
+	oidcLinkVerifiedEmail bool
 
 	dbSchema *schema.Schema
 	rules    map[string]schema.RuleExecutor
 }
 
 // NewAuth acts as constructor with the required params
+// if SessionStore is not provided, it'll use redis store when CacheClient is provided, otherwise sql store will be used
 func NewAuth(opts Options) *Auth {
+	sessionStore := opts.SessionStore
+	if sessionStore == nil {
+		if opts.CacheClient != nil {
+			sessionStore = session.NewRedisStore(opts.CacheClient)
+		} else {
+			sessionStore = session.NewSQLStore(opts.GuardSchema)
+		}
+	}
+
 	authModule := &Auth{
 		sessionName:      opts.SessionName,
 		dbSchema:         opts.GuardSchema,
-		cacheClient:      opts.CacheClient,
+		sessionStore:     sessionStore,
 		loginMethod:      opts.LoginMethod,
 		expiredInSeconds: opts.ExpiredInSec,
+		refreshInSeconds: opts.RefreshExpiredInSec,
 		tokenStrategy:    opts.TokenStrategy,
 		passwordStrategy: opts.PasswordStrategy,
-		rules:            make(map[string]schema.RuleExecutor),
+		passwordPolicy:   opts.PasswordPolicy,
+		jwtStrategy:      opts.JWTStrategy,
+		jwtDenylist:      opts.JWTDenylist,
+		throttle:         opts.Throttle,
+		csrf:             opts.CSRF,
+		cookie:           opts.Cookie,
+		errorHandler:     opts.ErrorHandler,
+		mfaIssuer:        opts.MFAIssuer,
+
+		notifier:               opts.Notifier,
+		passwordResetInSeconds: opts.PasswordResetExpiredInSec,
+
+		impersonatePermissionName: opts.ImpersonatePermission,
+
+		emailVerification:          opts.EmailVerification,
+		requireVerifiedEmail:       opts.RequireVerifiedEmail,
+		emailVerificationInSeconds: opts.EmailVerificationExpiredInSec,
+
+		oidcProviders:         make(map[string]*oidc.Provider),
+		oidcAutoRegister:      opts.OIDCAutoRegister,
+		oidcLinkVerifiedEmail: opts.OIDCLinkVerifiedEmail,
+
+		rules: make(map[string]schema.RuleExecutor),
+	}
+	for _, provider := range opts.OIDCProviders {
+		authModule.RegisterOIDCProvider(provider)
+	}
+	if authModule.errorHandler == nil {
+		authModule.errorHandler = DefaultErrorHandler
+	}
+	if authModule.jwtStrategy != nil && authModule.expiredInSeconds <= 0 {
+		authModule.expiredInSeconds = int64(defaultJWTTTL / time.Second)
 	}
 
 	return authModule
 }
 
+// sessionTTL will return the session lifetime as time.Duration
+func (a *Auth) sessionTTL() time.Duration {
+	return time.Duration(a.expiredInSeconds) * time.Second
+}
+
+// publish will publish the event to the event bus of guardian schema
+func (a *Auth) publish(e event.Event) {
+	a.dbSchema.Events.Publish(e)
+}
+
 // RegisterRule will register rule executor in the auth module
 func (a *Auth) RegisterRule(executor schema.RuleExecutor) {
 	if executor != nil {
@@ -98,25 +238,71 @@ func (a *Auth) RegisterRule(executor schema.RuleExecutor) {
 // Authenticate function will authenticate user by LoginParams and return user entity if user has successfully login
 // Authenticate function will get the data from database
 // if user exist, password request validated, and logged user has active status, then loggedUser entity will be returned, otherwise it'll return error
+// if throttling is enabled, the failed attempts are counted and *ErrAccountLocked will be returned when the identifier or ip address is locked,
+// *ErrLoginDelayed will be returned when the progressive delay after the failed attempts is not passed yet
+// if the password is older than the maximum password age, *ErrPasswordExpired will be returned and the password must be changed by ChangeExpiredPassword
+// if user has enabled mfa, *ErrMFARequired will be returned and the login must be completed by VerifyMFA
+// the rejected login is published as event.LoginFailed
 func (a *Auth) Authenticate(params LoginParams) (*schema.User, error) {
 	var loggedUser *schema.User
 	var err error
+	if a.throttle.enabled() {
+		loggedUser, err = a.authenticateThrottle(params)
+	} else {
+		loggedUser, err = a.authenticate(params)
+	}
+	if err != nil {
+		a.publish(event.LoginFailed{
+			Identifier: params.Identifier,
+			IPAddress:  params.IPAddress,
+			UserAgent:  params.UserAgent,
+			Reason:     err,
+			OccurredAt: time.Now(),
+		})
+		return nil, err
+	}
+
+	err = a.checkPasswordAge(loggedUser)
+	if err != nil {
+		return nil, err
+	}
 
+	err = a.challengeMFA(loggedUser, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// the login with mfa resets the failed attempts after the mfa code is verified
+	err = a.resetFailedLogin(params)
+	if err != nil {
+		return nil, err
+	}
+	return loggedUser, nil
+}
+
+// findUserByIdentifier will find user by email or username depend on the login method
+func (a *Auth) findUserByIdentifier(identifier string) (*schema.User, error) {
 	switch a.loginMethod {
 	case LoginEmail:
-		loggedUser, err = a.dbSchema.User(nil).
+		return a.dbSchema.User(nil).
 			FindUser(map[string]interface{}{
-				"email": params.Identifier,
+				"email": identifier,
 			})
 	case LoginUsername:
-		loggedUser, err = a.dbSchema.User(nil).
+		return a.dbSchema.User(nil).
 			FindUser(map[string]interface{}{
-				"username": params.Identifier,
+				"username": identifier,
 			})
 	case LoginEmailUsername:
-		loggedUser, err = a.dbSchema.User(nil).
-			FindUserByUsernameOrEmail(params.Identifier)
+		return a.dbSchema.User(nil).
+			FindUserByUsernameOrEmail(identifier)
 	}
+	return nil, nil
+}
+
+// authenticate will validate the login params without throttling
+func (a *Auth) authenticate(params LoginParams) (*schema.User, error) {
+	loggedUser, err := a.findUserByIdentifier(params.Identifier)
 	if loggedUser == nil {
 		return nil, ErrInvalidUserLogin
 	}
@@ -131,79 +317,89 @@ func (a *Auth) Authenticate(params LoginParams) (*schema.User, error) {
 	if !loggedUser.Active {
 		return nil, ErrUserNotActive
 	}
+
+	if a.requireVerifiedEmail && !loggedUser.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	a.rehashPassword(loggedUser, params.Password)
 	return loggedUser, nil
 }
 
+// rehashPassword will upgrade the stored password hash if the password strategy implements password.Rehasher
+// the login isn't rejected when the upgrade is failed, it'll be tried again on the next login.
+// the upgrade doesn't reset the password age, because the password isn't changed
+func (a *Auth) rehashPassword(user *schema.User, plainPassword string) {
+	rehasher, ok := a.passwordStrategy.(password.Rehasher)
+	if !ok || !rehasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	encrypted := a.passwordStrategy.HashPassword(plainPassword)
+	if encrypted == "" {
+		return
+	}
+	a.dbSchema.User(user).RehashPassword(encrypted)
+}
+
 // SignInCookie will authenticate user login and set the cookie with validated user session
-// It'll generate a cookie token with specific tokenStrategy and set the token in the redis with the specific key and expiredTime
+// It'll generate a cookie token with specific tokenStrategy and set the token in the session store with the specific key and expiredTime
 func (a *Auth) SignInCookie(w http.ResponseWriter, params LoginParams) (*schema.User, error) {
 	loggedUser, err := a.Authenticate(params)
 	if err != nil {
 		return nil, err
 	}
 
-	hashCookie := a.tokenStrategy.GenerateCookie()
-	http.SetCookie(w, &http.Cookie{
-		Name:    a.sessionName,
-		Value:   hashCookie,
-		Path:    "/",
-		Expires: time.Now().Add(time.Duration(a.expiredInSeconds)),
+	err = a.setSessionCookie(w, loggedUser, sessionParams{
+		isCookie:  true,
+		ipAddress: params.IPAddress,
+		userAgent: params.UserAgent,
 	})
-
-	err = a.cacheClient.Do(
-		"SETEX",
-		hashCookie,
-		strconv.FormatInt(a.expiredInSeconds, 10),
-		loggedUser.ID,
-	).Err()
 	if err != nil {
-		return nil, ErrCreatingCookie
+		return nil, err
 	}
-
 	return loggedUser, nil
 }
 
+// setSessionCookie will create the login session and set it as cookie
+func (a *Auth) setSessionCookie(w http.ResponseWriter, user *schema.User, params sessionParams) error {
+	hashCookie, err := a.newSession(user, params)
+	if err != nil {
+		return ErrCreatingCookie
+	}
+	return a.writeSessionCookie(w, hashCookie)
+}
+
 // ClearSession function will clear the login session with the provided cookie
-// It'll delete cookie in the redis db and set the empty cookie as response to user
+// It'll delete cookie in the session store and set the empty cookie as response to user
 func (a *Auth) ClearSession(w http.ResponseWriter, r *http.Request) error {
-	cookieData, err := r.Cookie(a.sessionName)
+	cookie, err := a.getSessionCookie(r)
 	if err != nil {
-		return ErrInvalidCookie
+		a.clearSessionCookie(w)
+		return err
 	}
-	cookie := cookieData.Value
-	err = a.cacheClient.Do(
-		"DEL",
-		cookie,
-	).Err()
+	err = a.revokeSession(cookie, true)
 	if err != nil {
 		return err
 	}
 
 	// clear cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:   a.sessionName,
-		Value:  "",
-		Path:   "/",
-		MaxAge: -1,
-	})
+	a.clearSessionCookie(w)
 	return nil
 }
 
 // SignInCookie will authenticate user login and return token string for authentication based token
-// It'll generate a token with specific tokenStrategy and set the token in the redis with the specific key and expiredTime
+// It'll generate a token with specific tokenStrategy and set the token in the session store with the specific key and expiredTime
 func (a *Auth) SignIn(params LoginParams) (*schema.User, string, error) {
 	loggedUser, err := a.Authenticate(params)
 	if err != nil {
 		return nil, "", err
 	}
 
-	token := a.tokenStrategy.GenerateToken()
-	err = a.cacheClient.Do(
-		"SETEX",
-		token,
-		strconv.FormatInt(a.expiredInSeconds, 10),
-		loggedUser.ID,
-	).Err()
+	token, err := a.newSession(loggedUser, sessionParams{
+		ipAddress: params.IPAddress,
+		userAgent: params.UserAgent,
+	})
 	if err != nil {
 		return nil, "", ErrCreatingToken
 	}
@@ -212,7 +408,7 @@ func (a *Auth) SignIn(params LoginParams) (*schema.User, string, error) {
 }
 
 // Logout function will clear the login session with the provided header Authorization
-// It'll delete token data in the redis db
+// It'll delete token data in the session store
 func (a *Auth) Logout(request *http.Request) error {
 	var err error
 	var user *schema.User
@@ -222,11 +418,11 @@ func (a *Auth) Logout(request *http.Request) error {
 		return ErrInvalidUserLogin
 	}
 
-	token := request.Header.Get(authorization)
-	err = a.cacheClient.Do(
-		"DEL",
-		token,
-	).Err()
+	token, err := getAuthorizationToken(request)
+	if err != nil {
+		return err
+	}
+	err = a.revokeSession(token, true)
 	if err != nil {
 		return err
 	}
@@ -234,7 +430,9 @@ func (a *Auth) Logout(request *http.Request) error {
 }
 
 // Register function will create a new user with hashed password that provided by auth module
+// the password is validated by the password policy if it's configured.
 // This function will return error that indicate user creation is success or not
+// if email verification is enabled, the email verification token will be sent to user by Notifier
 func (a *Auth) Register(user *schema.User) error {
 	if user.DBContract == nil {
 		user = a.dbSchema.User(user)
@@ -244,140 +442,121 @@ func (a *Auth) Register(user *schema.User) error {
 	if err != nil {
 		return err
 	}
+	if a.passwordPolicy != nil {
+		err = a.passwordPolicy.Validate(user.Password, user.Username, user.Email)
+		if err != nil {
+			return err
+		}
+	}
 
 	user.SetEncryptedPassword(a.passwordStrategy.HashPassword(user.Password))
-	return user.CreateUser()
-}
-
-/* HTTP Protection */
-func (a *Auth) authenticateRoute(w http.ResponseWriter, r *http.Request, strategy int) (*schema.User, error) {
-	user, err := a.getUserPrinciple(r, strategy)
+	err = user.CreateUser()
 	if err != nil {
-		switch strategy {
-		case CookieBasedAuth:
-			a.ClearSession(w, r)
-		}
-		w.WriteHeader(http.StatusUnauthorized)
-		return nil, err
+		return err
 	}
-	return user, nil
+	err = a.recordPassword(user)
+	if err != nil {
+		return err
+	}
+	a.publishRegistered(user)
+
+	if a.emailVerification {
+		return a.SendEmailVerification(user)
+	}
+	return nil
 }
 
+// publishRegistered will publish the new user as event.UserRegistered
+func (a *Auth) publishRegistered(user *schema.User) {
+	a.publish(event.UserRegistered{
+		UserID:     user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		OccurredAt: time.Now(),
+	})
+}
+
+/* HTTP Protection */
+
 // AuthenticateCookieHandler is a middleware func that protect the specific route handler using cookie based authentication
 func (a *Auth) AuthenticateCookieHandler(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, err := a.authenticateRoute(w, r, CookieBasedAuth)
-		if err != nil {
-			return
-		}
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler.ServeHTTP(w, r)
-	})
+	return a.Middleware(WithCredentialSources(CredentialCookie))(handler)
 }
 
 // AuthenticateCookieHandlerFunc is a middleware func that protect the specific route handler as handlerFunc using cookie based authentication
 func (a *Auth) AuthenticateCookieHandlerFunc(handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		user, err := a.authenticateRoute(w, r, CookieBasedAuth)
-		if err != nil {
-			return
-		}
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler(w, r)
-	}
+	return a.AuthenticateCookieHandler(http.HandlerFunc(handler)).ServeHTTP
 }
 
 // AuthenticateHandler is a middleware func that protect the specific route handler using token based authentication
 func (a *Auth) AuthenticateHandler(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, err := a.authenticateRoute(w, r, TokenBasedAuth)
-		if err != nil {
-			return
-		}
-
-		// execute all rules
-		err = a.executeRule(w, r, user, false)
-		if err != nil {
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler.ServeHTTP(w, r)
-	})
+	return a.Middleware(WithRules(true))(handler)
 }
 
 // AuthenticateHandlerFunc is a middleware func that protect the specific route handler as handlerFunc using token based authentication
 func (a *Auth) AuthenticateHandlerFunc(handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// authenticate user by route
-		user, err := a.authenticateRoute(w, r, TokenBasedAuth)
-		if err != nil {
-			return
-		}
-
-		// execute all rules
-		err = a.executeRule(w, r, user, false)
-		if err != nil {
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-		handler(w, r)
-	}
+	return a.AuthenticateHandler(http.HandlerFunc(handler)).ServeHTTP
 }
 
-// authenticateRBAC will authenticate user role and permission.
-// this function will execute all rules that associated with this specific role, and permission
-func (a *Auth) authenticateRBAC(w http.ResponseWriter, r *http.Request, user *schema.User) error {
-	if user == nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		return errors.New("user not found")
-	}
+// executeRule function will execute all rules that associated with permission or roles depend on isRbac flag
+// if isRbac is true, the permission is resolved from the user's permissions and the request will be rejected if there's no permission matched,
+// otherwise the permission is resolved from all permissions, and only the request that authenticated by api key is rejected if there's no permission matched.
+// the rules are only executed if withRules is true.
+// the returned request contains the path params extracted from the matched permission route
+func (a *Auth) executeRule(r *http.Request, user *schema.User, isRbac, withRules bool) (*http.Request, error) {
+	var rules []schema.Rule
+	var permission *schema.Permission
+	var err error
+	ctx := r.Context()
 
-	isAllowed, err := a.dbSchema.User(user).CanAccess(r.Method, r.URL.Path)
-	if err != nil || !isAllowed {
-		w.WriteHeader(http.StatusForbidden)
-		return errors.New("forbidden to access resource")
+	// resolve the most specific permission by request resource
+	if isRbac {
+		permission, err = a.dbSchema.User(user).GetAccessPermissionContext(ctx, r.Method, r.URL.Path)
+	} else {
+		permission, err = a.dbSchema.Permission(nil).GetPermissionByResourceContext(ctx, r.Method, r.URL.Path)
 	}
-
-	// execute all rules
-	err = a.executeRule(w, r, user, true)
 	if err != nil {
-		w.WriteHeader(http.StatusForbidden)
-		return errors.New("forbidden to access resource")
+		return r, err
+	}
+	if permission == nil {
+		// api key can't access the resource that isn't registered as permission, because it's out of its scope
+		if isRbac || GetAPIKey(r) != nil {
+			return r, ErrPermissionDenied
+		}
+		return r, nil
 	}
 
-	return nil
-}
+	// api key can only access the permissions in its scope
+	if apiKey := GetAPIKey(r); apiKey != nil {
+		allowed, err := apiKey.HasPermissionContext(ctx, permission)
+		if err != nil {
+			return r, err
+		}
+		if !allowed {
+			return r, ErrPermissionDenied
+		}
+	}
 
-// executeRule function will execute all rules that associated with permission or roles depend on isRbac flag
-func (a *Auth) executeRule(w http.ResponseWriter, r *http.Request, user *schema.User, isRbac bool) error {
-	var rules []schema.Rule
-	ctx := r.Context()
+	// expose the path params to the rule executors and handler
+	ctx = context.WithValue(ctx, PathParams, permission.RouteParams)
+	r = r.WithContext(ctx)
+	if !withRules {
+		return r, nil
+	}
 
 	// execute all rules associated with permission
-	permission, err := a.dbSchema.Permission(nil).GetPermissionByResource(r.Method, r.URL.Path)
+	rules, err = a.dbSchema.Rule(nil).GetPermissionRuleContext(ctx, *permission)
 	if err != nil {
-		return err
+		return r, err
 	}
-	rules, err = a.dbSchema.Rule(nil).GetPermissionRuleContext(ctx, *permission)
 	err = a.executeRules(r, user, rules)
 	if err != nil {
-		return err
+		return r, err
 	}
 
 	// break this process when not require rbac authentication
 	if !isRbac {
-		return nil
+		return r, nil
 	}
 
 	// execute all rules associated with roles
@@ -388,18 +567,18 @@ func (a *Auth) executeRule(w http.ResponseWriter, r *http.Request, user *schema.
 		r.URL.Path,
 	)
 	if err != nil {
-		return err
+		return r, err
 	}
-	rules, err = a.dbSchema.Rule(nil).GetRolesRule(roles)
+	rules, err = a.dbSchema.Rule(nil).GetRolesRuleContext(ctx, roles)
 	if err != nil {
-		return err
+		return r, err
 	}
 
 	err = a.executeRules(r, user, rules)
 	if err != nil {
-		return err
+		return r, err
 	}
-	return nil
+	return r, nil
 }
 
 // executeRules will execute all rule in rules collection
@@ -408,7 +587,7 @@ func (a *Auth) executeRules(r *http.Request, user *schema.User, rules []schema.R
 		if ruleExecutor, ok := a.rules[rule.Name]; ok {
 			isRuleAllowed := ruleExecutor.Execute(user, &rule, r)
 			if !isRuleAllowed {
-				return errors.New(fmt.Sprintf("blocked by rule %s", ruleExecutor.Name()))
+				return &ErrBlockedByRule{Rule: ruleExecutor.Name()}
 			}
 		}
 	}
@@ -417,111 +596,36 @@ func (a *Auth) executeRules(r *http.Request, user *schema.User, rules []schema.R
 
 // AuthenticateRBACCookieHandler is a middleware func that protect the specific route handler using cookie based authentication and RBAC
 func (a *Auth) AuthenticateRBACCookieHandler(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var user *schema.User
-
-		user, err = a.authenticateRoute(w, r, CookieBasedAuth)
-		if err != nil {
-			return
-		}
-
-		err = a.authenticateRBAC(w, r, user)
-		if err != nil {
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler.ServeHTTP(w, r)
-	})
+	return a.Middleware(WithCredentialSources(CredentialCookie), WithRBAC(true), WithRules(true))(handler)
 }
 
 // AuthenticateRBACCookieHandlerFunc is a middleware func that protect the specific route handler as HandlerFunc using cookie based authentication and RBAC
 func (a *Auth) AuthenticateRBACCookieHandlerFunc(handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var user *schema.User
-
-		user, err = a.authenticateRoute(w, r, CookieBasedAuth)
-		if err != nil {
-			return
-		}
-
-		err = a.authenticateRBAC(w, r, user)
-		if err != nil {
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler(w, r)
-	}
+	return a.AuthenticateRBACCookieHandler(http.HandlerFunc(handler)).ServeHTTP
 }
 
 // AuthenticateRBACHandler is a middleware func that protect the specific route handler using token based authentication and RBAC
 func (a *Auth) AuthenticateRBACHandler(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var user *schema.User
-
-		user, err = a.authenticateRoute(w, r, TokenBasedAuth)
-		if err != nil {
-			return
-		}
-
-		err = a.authenticateRBAC(w, r, user)
-		if err != nil {
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler.ServeHTTP(w, r)
-	})
+	return a.Middleware(WithRBAC(true), WithRules(true))(handler)
 }
 
 // AuthenticateRBACHandlerFunc is a middleware func that protect the specific route handler as HandlerFunc using token based authentication and RBAC
 func (a *Auth) AuthenticateRBACHandlerFunc(handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var user *schema.User
-
-		user, err = a.authenticateRoute(w, r, TokenBasedAuth)
-		if err != nil {
-			return
-		}
-
-		err = a.authenticateRBAC(w, r, user)
-		if err != nil {
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserPrinciple, user)
-		r = r.WithContext(ctx)
-
-		handler(w, r)
-	}
+	return a.AuthenticateRBACHandler(http.HandlerFunc(handler)).ServeHTTP
 }
 
 // VerifyToken is helper function to get UserID by token string
-// This function will get the data from redis database
-func (a *Auth) VerifyToken(token string) (int64, error) {
-	result, err := a.cacheClient.Do(
-		"GET",
-		token,
-	).Int64()
+// This function will get the data from session store, or verify the claims if the token is jwt token
+func (a *Auth) VerifyToken(tokenStr string) (int64, error) {
+	record, err := a.verifySession(tokenStr)
 	if err != nil {
 		return -1, err
 	}
-	return result, nil
+	return record.UserID, nil
 }
 
 // GetUserByToken is helper function to get User entity by token string
-// This function will get the data from redis and relational databases
+// This function will get the data from session store and relational databases
 func (a *Auth) GetUserByToken(token string) (*schema.User, error) {
 	userId, err := a.VerifyToken(token)
 	if err != nil {
@@ -537,37 +641,32 @@ func (a *Auth) GetUserByToken(token string) (*schema.User, error) {
 	return user, nil
 }
 
-// getUserPrinciple is non exported helper function to get logged user by http request and strategy
-func (a *Auth) getUserPrinciple(r *http.Request, strategy int) (*schema.User, error) {
-	var token string
-	switch strategy {
-	case CookieBasedAuth:
-		cookieData, err := r.Cookie(a.sessionName)
-		if err != nil {
-			return nil, ErrInvalidCookie
-		}
-		token = cookieData.Value
-	case TokenBasedAuth:
-		rawToken := r.Header.Get(authorization)
-		headers := strings.Split(rawToken, " ")
-		if len(headers) != 2 {
-			return nil, ErrInvalidAuthorization
-		}
-		token = headers[1]
+// GetPathParams is helper function to get path params that extracted from the matched permission route
+// e.g. permission route `/users/{id}` with request path `/users/42` will return {"id": "42"}
+// You should using middleware authentication before call this function
+// If not it'll return empty path params
+func GetPathParams(r *http.Request) map[string]string {
+	params, ok := r.Context().Value(PathParams).(map[string]string)
+	if !ok || params == nil {
+		return map[string]string{}
 	}
+	return params
+}
 
-	userID, err := a.VerifyToken(token)
-	if err != nil {
-		return nil, ErrValidateCookie
-	}
-	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
-		"id": userID,
-	})
-	if err != nil {
-		return nil, ErrUserNotFound
+// getAuthorization is non exported helper function to get the scheme and credential from header Authorization
+func getAuthorization(r *http.Request) (string, string, error) {
+	rawToken := r.Header.Get(authorization)
+	headers := strings.Split(rawToken, " ")
+	if len(headers) != 2 {
+		return "", "", ErrInvalidAuthorization
 	}
+	return headers[0], headers[1], nil
+}
 
-	return user, nil
+// getAuthorizationToken is non exported helper function to get the token from header Authorization
+func getAuthorizationToken(r *http.Request) (string, error) {
+	_, token, err := getAuthorization(r)
+	return token, err
 }
 
 // GetUserLogin is helper function to get user entity by request
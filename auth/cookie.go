@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	hostCookiePrefix  = "__Host-"
+	defaultCookiePath = "/"
+)
+
+// CookieOptions contains the attributes of session cookie that set by SignInCookie and cleared by ClearSession
+type CookieOptions struct {
+	Domain   string
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+
+	// MaxAge is lifetime of cookie in seconds, default is the session lifetime.
+	// the cookie is deleted when the browser is closed if both MaxAge and session lifetime <= 0
+	MaxAge int
+
+	// HostPrefix will add `__Host-` prefix to the cookie name,
+	// the cookie is always secure, has `/` path and doesn't have domain
+	HostPrefix bool
+
+	// SigningKey is used to sign the cookie value with HMAC-SHA256
+	// EncryptionKey is used to encrypt the cookie value with AES-GCM, the key must be 16, 24 or 32 bytes
+	SigningKey    []byte
+	EncryptionKey []byte
+}
+
+// cookieName will return the name of session cookie
+func (a *Auth) cookieName() string {
+	if a.cookie.HostPrefix {
+		return hostCookiePrefix + a.sessionName
+	}
+	return a.sessionName
+}
+
+// newCookie will create the session cookie with the configured attributes
+func (a *Auth) newCookie(value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     a.cookieName(),
+		Value:    value,
+		Domain:   a.cookie.Domain,
+		Path:     a.cookie.Path,
+		Secure:   a.cookie.Secure,
+		HttpOnly: a.cookie.HttpOnly,
+		SameSite: a.cookie.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = defaultCookiePath
+	}
+	if a.cookie.HostPrefix {
+		cookie.Domain = ""
+		cookie.Path = defaultCookiePath
+		cookie.Secure = true
+	}
+	return cookie
+}
+
+// writeSessionCookie will set the session cookie with the token
+func (a *Auth) writeSessionCookie(w http.ResponseWriter, sessionToken string) error {
+	value, err := a.encodeCookie(sessionToken)
+	if err != nil {
+		return ErrCreatingCookie
+	}
+
+	cookie := a.newCookie(value)
+	maxAge := a.cookie.MaxAge
+	if maxAge <= 0 {
+		maxAge = int(a.expiredInSeconds)
+	}
+	if maxAge > 0 {
+		cookie.MaxAge = maxAge
+		cookie.Expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// clearSessionCookie will set the expired session cookie, so the browser will delete it
+func (a *Auth) clearSessionCookie(w http.ResponseWriter) {
+	cookie := a.newCookie("")
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(w, cookie)
+}
+
+// getSessionCookie will return the session token from the session cookie of request
+func (a *Auth) getSessionCookie(r *http.Request) (string, error) {
+	cookieData, err := r.Cookie(a.cookieName())
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return a.decodeCookie(cookieData.Value)
+}
+
+// encodeCookie will encrypt and sign the cookie value if the keys are configured
+func (a *Auth) encodeCookie(value string) (string, error) {
+	if len(a.cookie.EncryptionKey) > 0 {
+		aead, err := a.cookieCipher()
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+		sealed := aead.Seal(nonce, nonce, []byte(value), []byte(a.cookieName()))
+		value = base64.RawURLEncoding.EncodeToString(sealed)
+	}
+	if len(a.cookie.SigningKey) > 0 {
+		value = value + "." + base64.RawURLEncoding.EncodeToString(a.cookieSignature(value))
+	}
+	return value, nil
+}
+
+// decodeCookie will verify and decrypt the cookie value if the keys are configured
+func (a *Auth) decodeCookie(value string) (string, error) {
+	if len(a.cookie.SigningKey) > 0 {
+		index := strings.LastIndex(value, ".")
+		if index < 0 {
+			return "", ErrInvalidCookie
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(value[index+1:])
+		if err != nil {
+			return "", ErrInvalidCookie
+		}
+		value = value[:index]
+		if !hmac.Equal(signature, a.cookieSignature(value)) {
+			return "", ErrInvalidCookie
+		}
+	}
+	if len(a.cookie.EncryptionKey) > 0 {
+		aead, err := a.cookieCipher()
+		if err != nil {
+			return "", err
+		}
+		sealed, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", ErrInvalidCookie
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(a.cookieName()))
+		if err != nil {
+			return "", ErrInvalidCookie
+		}
+		value = string(plain)
+	}
+	return value, nil
+}
+
+// cookieSignature will return HMAC-SHA256 signature of cookie name and value
+func (a *Auth) cookieSignature(value string) []byte {
+	mac := hmac.New(sha256.New, a.cookie.SigningKey)
+	mac.Write([]byte(a.cookieName() + "=" + value))
+	return mac.Sum(nil)
+}
+
+// cookieCipher will return AES-GCM cipher of encryption key
+func (a *Auth) cookieCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(a.cookie.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
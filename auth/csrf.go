@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrInvalidCSRFToken = errors.New("invalid csrf token")
+)
+
+const (
+	defaultCSRFHeaderName = "X-CSRF-Token"
+	defaultCSRFFieldName  = "csrf_token"
+)
+
+// CSRFOptions contains configuration for csrf protection of cookie based authentication
+// the csrf token is generated when the cookie session is created and stored in the session (synchronizer token),
+// every unsafe request (other than GET, HEAD, OPTIONS and TRACE) must send the token by header or form field.
+// the csrf protection is disabled if Enabled is false
+type CSRFOptions struct {
+	Enabled bool
+
+	// HeaderName is the request header that contains csrf token, default is `X-CSRF-Token`
+	HeaderName string
+
+	// FieldName is the form field that contains csrf token, default is `csrf_token`
+	FieldName string
+
+	// ExemptRoutes is the route patterns that skip the csrf protection, e.g. `/webhooks/*`
+	// the pattern has the same format as permission route
+	ExemptRoutes []string
+}
+
+// headerName will return the request header that contains csrf token
+func (c *CSRFOptions) headerName() string {
+	if c.HeaderName == "" {
+		return defaultCSRFHeaderName
+	}
+	return c.HeaderName
+}
+
+// fieldName will return the form field that contains csrf token
+func (c *CSRFOptions) fieldName() string {
+	if c.FieldName == "" {
+		return defaultCSRFFieldName
+	}
+	return c.FieldName
+}
+
+// isExempt will check the request path is exempted from csrf protection or not
+func (c *CSRFOptions) isExempt(path string) bool {
+	for _, route := range c.ExemptRoutes {
+		if _, ok := schema.MatchRoute(route, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeMethod will check the http method doesn't change the state or not
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// verifyCSRF will validate the csrf token of request with the token of session
+// the session without csrf token, e.g. session that created before csrf protection is enabled, can't be used for unsafe request
+func (a *Auth) verifyCSRF(r *http.Request, record *sessionRecord) error {
+	if !a.csrf.Enabled || isSafeMethod(r.Method) || a.csrf.isExempt(r.URL.Path) {
+		return nil
+	}
+	if record == nil || record.CSRFToken == "" {
+		return ErrInvalidCSRFToken
+	}
+
+	requestToken := r.Header.Get(a.csrf.headerName())
+	if requestToken == "" {
+		requestToken = r.PostFormValue(a.csrf.fieldName())
+	}
+	if subtle.ConstantTimeCompare([]byte(requestToken), []byte(record.CSRFToken)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+	return nil
+}
+
+// GetCSRFToken is helper function to get the csrf token of current session by request, e.g. to render it in the form template
+// You should using cookie based middleware authentication before call this function
+// If not it'll return empty string
+func GetCSRFToken(r *http.Request) string {
+	csrfToken, ok := r.Context().Value(CSRFToken).(string)
+	if !ok {
+		return ""
+	}
+	return csrfToken
+}
+
+// SessionCSRFToken will return the csrf token of the session cookie in the request
+// It can be used in the route that isn't protected by cookie based middleware authentication
+func (a *Auth) SessionCSRFToken(r *http.Request) (string, error) {
+	cookie, err := a.getSessionCookie(r)
+	if err != nil {
+		return "", err
+	}
+	record, err := a.lookupSession(cookie)
+	if err != nil {
+		return "", ErrValidateCookie
+	}
+	return record.CSRFToken, nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingDomain = errors.New("missing domain of request")
+)
+
+// DomainResolver will resolve the domain (tenant) of request, empty domain is returned if the request has no domain
+type DomainResolver func(r *http.Request) string
+
+// DomainFromHeader will resolve the domain from the request header, e.g. `X-Tenant-ID: acme`
+func DomainFromHeader(name string) DomainResolver {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// DomainFromSubdomain will resolve the domain from the subdomain of base domain,
+// e.g. host `acme.example.com` with base domain `example.com` is resolved as `acme`.
+// the host with nested subdomain or without subdomain has no domain
+func DomainFromSubdomain(baseDomain string) DomainResolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(r *http.Request) string {
+		host := strings.ToLower(r.Host)
+		if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+			host = host[:i]
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		subdomain := strings.TrimSuffix(host, suffix)
+		if strings.Contains(subdomain, ".") {
+			return ""
+		}
+		return subdomain
+	}
+}
+
+// DomainFromPathPrefix will resolve the domain from the path segment after prefix,
+// e.g. path `/tenants/acme/orders` with prefix `/tenants` is resolved as `acme`
+func DomainFromPathPrefix(prefix string) DomainResolver {
+	prefix = "/" + strings.Trim(prefix, "/") + "/"
+	if prefix == "//" {
+		prefix = "/"
+	}
+	return func(r *http.Request) string {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			return ""
+		}
+		segment := strings.TrimPrefix(r.URL.Path, prefix)
+		if i := strings.Index(segment, "/"); i >= 0 {
+			segment = segment[:i]
+		}
+		return segment
+	}
+}
+
+// GetDomain is helper function to get the domain of request that resolved by the middleware
+// You should using middleware with WithDomain option before call this function
+// If not it'll return empty domain
+func GetDomain(r *http.Request) string {
+	domain, ok := r.Context().Value(Domain).(string)
+	if !ok {
+		return ""
+	}
+	return domain
+}
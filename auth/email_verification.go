@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+)
+
+const defaultEmailVerificationTTL = 24 * time.Hour
+
+// emailVerificationTTL will return the lifetime of email verification token
+func (a *Auth) emailVerificationTTL() time.Duration {
+	if a.emailVerificationInSeconds <= 0 {
+		return defaultEmailVerificationTTL
+	}
+	return time.Duration(a.emailVerificationInSeconds) * time.Second
+}
+
+// SendEmailVerification will create the email verification token and deliver it to user by Notifier
+// It can be used to resend the token, the previous token of user can't be used anymore
+func (a *Auth) SendEmailVerification(user *schema.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	return a.issueUserToken(NotificationEmailVerification, user, a.emailVerificationTTL())
+}
+
+// VerifyEmail will mark the email of user as verified with the email verification token
+// the token can only be used once, and it's invalid if the email of user has been changed
+func (a *Auth) VerifyEmail(token string) error {
+	record, err := a.takeUserToken(NotificationEmailVerification, token)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": record.UserID,
+	})
+	if err != nil {
+		a.restoreUserToken(NotificationEmailVerification, token, record)
+		return err
+	}
+	if user == nil || user.Email != record.Email {
+		return ErrInvalidVerificationToken
+	}
+
+	if !user.IsEmailVerified() {
+		err = user.VerifyEmail()
+		if err != nil {
+			a.restoreUserToken(NotificationEmailVerification, token, record)
+			return err
+		}
+	}
+	return a.clearUserTokenIndex(NotificationEmailVerification, user.ID)
+}
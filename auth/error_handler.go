@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingCredential = errors.New("missing credential")
+	ErrSessionExpired    = errors.New("session is expired or revoked")
+	ErrPermissionDenied  = errors.New("forbidden to access resource")
+)
+
+const (
+	ErrorCodeMissingCredential      = "missing_credential"
+	ErrorCodeInvalidToken           = "invalid_token"
+	ErrorCodeSessionExpired         = "session_expired"
+	ErrorCodeUserNotFound           = "user_not_found"
+	ErrorCodeUserInactive           = "user_inactive"
+	ErrorCodeInvalidCSRFToken       = "invalid_csrf_token"
+	ErrorCodeImpersonationForbidden = "impersonation_forbidden"
+	ErrorCodePermissionDenied       = "permission_denied"
+	ErrorCodeBlockedByRule          = "blocked_by_rule"
+	ErrorCodeMissingDomain          = "missing_domain"
+	ErrorCodeInternal               = "internal_error"
+
+	problemContentType = "application/problem+json"
+	defaultRealm       = "guardian"
+)
+
+// ErrBlockedByRule is returned when the request is rejected by the rule executor
+type ErrBlockedByRule struct {
+	Rule string
+}
+
+func (e *ErrBlockedByRule) Error() string {
+	return fmt.Sprintf("blocked by rule %s", e.Rule)
+}
+
+// AuthError is the error that passed to ErrorHandler when the middleware rejects the request
+// Err is the reason, e.g. ErrMissingCredential, ErrSessionExpired, ErrUserNotActive, ErrPermissionDenied, ErrMissingDomain or *ErrBlockedByRule.
+// Challenge is the value of `WWW-Authenticate` header, it's only set for token based authentication with status 401
+type AuthError struct {
+	Status    int
+	Code      string
+	Challenge string
+	Err       error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+// ErrorHandler will write the response of rejected request
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err *AuthError)
+
+// Problem is the error response body that described by RFC 7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// newAuthError will classify the error of middleware by http status and error code
+// the bearer challenge is set for status 401 if the middleware accepts the token from header Authorization
+func newAuthError(err error, bearer bool) *AuthError {
+	authErr := &AuthError{Err: err}
+	switch err {
+	case ErrMissingCredential:
+		authErr.Status, authErr.Code = http.StatusUnauthorized, ErrorCodeMissingCredential
+	case ErrInvalidAuthorization, ErrInvalidCookie, ErrValidateCookie, ErrInvalidAPIKey, ErrAPIKeyExpired:
+		authErr.Status, authErr.Code = http.StatusUnauthorized, ErrorCodeInvalidToken
+	case ErrSessionExpired:
+		authErr.Status, authErr.Code = http.StatusUnauthorized, ErrorCodeSessionExpired
+	case ErrUserNotFound:
+		authErr.Status, authErr.Code = http.StatusUnauthorized, ErrorCodeUserNotFound
+	case ErrUserNotActive:
+		authErr.Status, authErr.Code = http.StatusForbidden, ErrorCodeUserInactive
+	case ErrInvalidCSRFToken:
+		authErr.Status, authErr.Code = http.StatusForbidden, ErrorCodeInvalidCSRFToken
+	case ErrImpersonationForbidden:
+		authErr.Status, authErr.Code = http.StatusForbidden, ErrorCodeImpersonationForbidden
+	case ErrPermissionDenied:
+		authErr.Status, authErr.Code = http.StatusForbidden, ErrorCodePermissionDenied
+	case ErrMissingDomain:
+		authErr.Status, authErr.Code = http.StatusBadRequest, ErrorCodeMissingDomain
+	default:
+		if _, ok := err.(*ErrBlockedByRule); ok {
+			authErr.Status, authErr.Code = http.StatusForbidden, ErrorCodeBlockedByRule
+		} else {
+			authErr.Status, authErr.Code = http.StatusInternalServerError, ErrorCodeInternal
+		}
+	}
+
+	if bearer && authErr.Status == http.StatusUnauthorized {
+		authErr.Challenge = bearerChallenge(authErr)
+	}
+	return authErr
+}
+
+// bearerChallenge will return the `WWW-Authenticate` header value that described by RFC 6750
+// the error attribute isn't included when the request doesn't contain the credential
+func bearerChallenge(err *AuthError) string {
+	challenge := fmt.Sprintf(`Bearer realm="%s"`, defaultRealm)
+	if err.Code == ErrorCodeMissingCredential {
+		return challenge
+	}
+	description := strings.Replace(err.Err.Error(), `"`, `'`, -1)
+	return challenge + fmt.Sprintf(`, error="invalid_token", error_description="%s"`, description)
+}
+
+// handleError will pass the error of middleware to the error handler
+func (a *Auth) handleError(w http.ResponseWriter, r *http.Request, err error, bearer bool) {
+	a.errorHandler(w, r, newAuthError(err, bearer))
+}
+
+// DefaultErrorHandler will write the error as RFC 7807 problem+json response
+// the detail of internal error isn't exposed to the client
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err *AuthError) {
+	if err.Challenge != "" {
+		w.Header().Set("WWW-Authenticate", err.Challenge)
+	}
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(err.Status),
+		Status:   err.Status,
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+		Code:     err.Code,
+	}
+	if err.Status >= http.StatusInternalServerError {
+		problem.Detail = ""
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(problem)
+}
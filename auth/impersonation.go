@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/event"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrImpersonationForbidden = errors.New("user is not allowed to impersonate")
+	ErrInvalidImpersonation   = errors.New("invalid impersonation target")
+	ErrNotImpersonating       = errors.New("session is not impersonated")
+)
+
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+
+	defaultImpersonatePermission = "impersonate_user"
+)
+
+// impersonatePermission will return the permission name that required to impersonate
+func (a *Auth) impersonatePermission() string {
+	if a.impersonatePermissionName == "" {
+		return defaultImpersonatePermission
+	}
+	return a.impersonatePermissionName
+}
+
+// canImpersonate will check the admin has the impersonate permission and return the target user
+// the user that has the impersonate permission can't be impersonated, so the admin can't escalate the privilege
+func (a *Auth) canImpersonate(admin *schema.User, targetUserID int64) (*schema.User, error) {
+	if admin == nil {
+		return nil, ErrInvalidUserLogin
+	}
+	if admin.ID == targetUserID {
+		return nil, ErrInvalidImpersonation
+	}
+
+	allowed, err := a.dbSchema.User(admin).HasPermission(a.impersonatePermission())
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrImpersonationForbidden
+	}
+
+	target, err := a.findUserByID(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !target.Active {
+		return nil, ErrUserNotActive
+	}
+	privileged, err := a.dbSchema.User(target).HasPermission(a.impersonatePermission())
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return nil, ErrInvalidImpersonation
+	}
+	return target, nil
+}
+
+// startImpersonation will create the session of target user that marked with the admin id and record it in the audit log
+// the impersonation is refused if the session can't be revoked, i.e. jwt token without the denylist,
+// because the impersonation can't be stopped and the token would be valid as the normal session of target user
+func (a *Auth) startImpersonation(admin *schema.User, targetUserID int64, params sessionParams) (*schema.User, *sessionRecord, error) {
+	if a.jwtStrategy != nil && !a.jwtDenylist {
+		return nil, nil, ErrTokenNotRevocable
+	}
+
+	target, err := a.canImpersonate(admin, targetUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params.impersonatorID = admin.ID
+	record, err := a.createSessionRecord(target, params)
+	if err != nil {
+		return nil, nil, ErrCreatingToken
+	}
+
+	err = a.dbSchema.AuditLog(&schema.AuditLog{
+		ActorID:   admin.ID,
+		UserID:    target.ID,
+		Action:    AuditImpersonationStart,
+		SessionID: record.ID,
+		IPAddress: params.ipAddress,
+	}).CreateAuditLog()
+	if err != nil {
+		a.revokeSessionRecord(record, true)
+		return nil, nil, err
+	}
+
+	a.publish(event.ImpersonationStarted{
+		ImpersonatorID: admin.ID,
+		UserID:         target.ID,
+		SessionID:      record.ID,
+		OccurredAt:     record.CreatedAt,
+	})
+	return target, record, nil
+}
+
+// Impersonate will create the login session of target user for admin and return token string for authentication based token
+// the admin must have the impersonate permission, and the session is marked with the admin id.
+// the start of impersonation is recorded in the audit log.
+// ErrTokenNotRevocable will be returned if jwt strategy is set without the denylist
+func (a *Auth) Impersonate(adminUser *schema.User, targetUserID int64) (*schema.User, string, error) {
+	target, record, err := a.startImpersonation(adminUser, targetUserID, sessionParams{})
+	if err != nil {
+		return nil, "", err
+	}
+	return target, record.Token, nil
+}
+
+// ImpersonateCookie will replace the session cookie of logged admin with the session of target user
+// the session of admin is kept, so it can be restored by StopImpersonationCookie.
+// You should using cookie based middleware authentication before call this function
+func (a *Auth) ImpersonateCookie(w http.ResponseWriter, r *http.Request, targetUserID int64) (*schema.User, error) {
+	if GetImpersonator(r) != nil {
+		return nil, ErrInvalidImpersonation
+	}
+
+	params := requestSessionParams(r)
+	params.isCookie = true
+	params.impersonatorSessionID = GetSessionID(r)
+	target, record, err := a.startImpersonation(GetUserLogin(r), targetUserID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.writeSessionCookie(w, record.Token)
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// stopImpersonation will revoke the impersonated session of request and record it in the audit log
+func (a *Auth) stopImpersonation(r *http.Request) (*sessionRecord, error) {
+	sessionID := GetSessionID(r)
+	if sessionID == "" {
+		return nil, ErrNotImpersonating
+	}
+	record, err := a.getSessionRecord(sessionID)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, ErrNotImpersonating
+		}
+		return nil, err
+	}
+	if record.ImpersonatorID == 0 {
+		return nil, ErrNotImpersonating
+	}
+
+	err = a.revokeSessionRecord(record, true)
+	if err != nil {
+		return nil, err
+	}
+	err = a.dbSchema.AuditLog(&schema.AuditLog{
+		ActorID:   record.ImpersonatorID,
+		UserID:    record.UserID,
+		Action:    AuditImpersonationStop,
+		SessionID: record.ID,
+		IPAddress: requestSessionParams(r).ipAddress,
+	}).CreateAuditLog()
+	if err != nil {
+		return nil, err
+	}
+
+	a.publish(event.ImpersonationStopped{
+		ImpersonatorID: record.ImpersonatorID,
+		UserID:         record.UserID,
+		SessionID:      record.ID,
+		OccurredAt:     time.Now(),
+	})
+	return record, nil
+}
+
+// StopImpersonation will revoke the impersonated session of request
+// the end of impersonation is recorded in the audit log.
+// You should using middleware authentication before call this function
+func (a *Auth) StopImpersonation(r *http.Request) error {
+	_, err := a.stopImpersonation(r)
+	return err
+}
+
+// StopImpersonationCookie will revoke the impersonated session and restore the session cookie of admin
+// the session cookie is cleared if the session of admin has been expired.
+// You should using cookie based middleware authentication before call this function
+func (a *Auth) StopImpersonationCookie(w http.ResponseWriter, r *http.Request) error {
+	record, err := a.stopImpersonation(r)
+	if err != nil {
+		return err
+	}
+
+	if record.ImpersonatorSessionID != "" {
+		adminRecord, err := a.getSessionRecord(record.ImpersonatorSessionID)
+		if err == nil {
+			return a.writeSessionCookie(w, adminRecord.Token)
+		}
+		if err != session.ErrSessionNotFound {
+			return err
+		}
+	}
+	a.clearSessionCookie(w)
+	return nil
+}
+
+// verifyImpersonator will return the admin of impersonated session
+// the session is invalid if the admin isn't active or doesn't have the impersonate permission anymore
+func (a *Auth) verifyImpersonator(record *sessionRecord) (*schema.User, error) {
+	impersonator, err := a.findUserByID(record.ImpersonatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !impersonator.Active {
+		return nil, ErrUserNotActive
+	}
+	allowed, err := a.dbSchema.User(impersonator).HasPermission(a.impersonatePermission())
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrImpersonationForbidden
+	}
+	return impersonator, nil
+}
+
+// GetImpersonator is helper function to get the admin that impersonates the logged user by request
+// You should using middleware authentication before call this function
+// It'll return nil if the session isn't impersonated
+func GetImpersonator(r *http.Request) *schema.User {
+	impersonator, ok := r.Context().Value(ImpersonatorPrinciple).(*schema.User)
+	if !ok {
+		return nil
+	}
+	return impersonator
+}
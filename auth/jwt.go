@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/auth/token"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrTokenRevoked      = errors.New("token has been revoked")
+	ErrTokenNotRevocable = errors.New("jwt token can't be revoked without the denylist")
+)
+
+const (
+	jwtDenylistPrefix = "guardian:jwt_deny:"
+
+	// defaultJWTTTL is the lifetime of jwt token when the session lifetime isn't set, the jwt token must be expired
+	defaultJWTTTL = 24 * time.Hour
+
+	// jwtDenylistMarker is the value of revoked token id in the denylist, only the existence of key is checked
+	jwtDenylistMarker = "revoked"
+)
+
+// signJWT will create a signed jwt token that contains session id, user id, role names and expiration time as claims
+// the impersonator and csrf token of session are included, so the token can be verified without the session record
+func (a *Auth) signJWT(user *schema.User, record *sessionRecord) (string, error) {
+	roles, err := a.dbSchema.User(user).GetRoles()
+	if err != nil {
+		return "", err
+	}
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	return a.jwtStrategy.Sign(token.Claims{
+		ID:        record.ID,
+		Subject:   strconv.FormatInt(user.ID, 10),
+		UserID:    user.ID,
+		Roles:     roleNames,
+		IssuedAt:  record.CreatedAt.Unix(),
+		ExpiresAt: record.CreatedAt.Add(a.sessionTTL()).Unix(),
+
+		ImpersonatorID: record.ImpersonatorID,
+		CSRFToken:      record.CSRFToken,
+	})
+}
+
+// claimsSession will return the session record of jwt token by its claims
+// the metadata that isn't in the claims, e.g. ip address and refresh token family, is only in the stored session record
+func claimsSession(claims *token.Claims, tokenStr string) *sessionRecord {
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	return &sessionRecord{
+		Session: Session{
+			ID:         claims.ID,
+			UserID:     claims.UserID,
+			CreatedAt:  issuedAt,
+			LastSeenAt: issuedAt,
+			ExpiredAt:  claims.ExpiredTime(),
+
+			ImpersonatorID: claims.ImpersonatorID,
+		},
+		Token:     tokenStr,
+		CSRFToken: claims.CSRFToken,
+	}
+}
+
+// verifyJWT will verify the jwt token and return the claims
+// if the denylist is enabled, it'll check the token id in the session store
+func (a *Auth) verifyJWT(tokenStr string) (*token.Claims, error) {
+	claims, err := a.jwtStrategy.Verify(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.jwtDenylist {
+		_, err = a.sessionStore.Get(jwtDenylistPrefix + claims.ID)
+		if err == nil {
+			return nil, ErrTokenRevoked
+		}
+		if err != session.ErrSessionNotFound {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// revokeJWT will put the token id in the denylist until the token is expired and can't be accepted anymore
+// stateless jwt token can't be revoked if the denylist is disabled, so ErrTokenNotRevocable will be returned
+func (a *Auth) revokeJWT(tokenStr string) error {
+	if !a.jwtDenylist {
+		return ErrTokenNotRevocable
+	}
+
+	claims, err := a.jwtStrategy.Verify(tokenStr)
+	if err != nil {
+		// invalid or expired token can't be used anymore
+		return nil
+	}
+	// the token is accepted by Verify until the expiration time plus the leeway
+	ttl := time.Until(claims.ExpiredTime().Add(a.jwtStrategy.Leeway))
+	if ttl <= 0 {
+		return nil
+	}
+	return a.sessionStore.Put(jwtDenylistPrefix+claims.ID, jwtDenylistMarker, ttl)
+}
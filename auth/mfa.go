@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/mfa"
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrMFANotEnrolled      = errors.New("mfa is not enrolled")
+	ErrMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid mfa code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+)
+
+const (
+	mfaChallengePrefix = "guardian:mfa_challenge:"
+	mfaChallengeTTL    = 5 * time.Minute
+	mfaMaxAttempts     = 5
+	recoveryCodeCount  = 10
+)
+
+// ErrMFARequired is returned by Authenticate when the password is valid but user has enabled mfa
+// the login must be completed by VerifyMFA, VerifyMFACookie or VerifyMFAWithRefresh with ChallengeToken
+type ErrMFARequired struct {
+	ChallengeToken string
+	ExpiredAt      time.Time
+}
+
+func (e *ErrMFARequired) Error() string {
+	return "mfa verification is required"
+}
+
+// MFAEnrollment contains the TOTP secret that should be added to the authenticator app
+// URI is `otpauth://` uri that can be rendered as QR code
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// mfaChallenge represents the pending login that waiting for mfa verification
+// the identifier is kept, so the failed mfa code is counted in the failed attempts of login
+type mfaChallenge struct {
+	UserID     int64     `json:"user_id"`
+	Identifier string    `json:"identifier"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Attempts   int       `json:"attempts"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+// challengeMFA will create the mfa challenge if user has enabled mfa
+// *ErrMFARequired will be returned with the challenge token
+func (a *Auth) challengeMFA(user *schema.User, params LoginParams) error {
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return err
+	}
+	if userMFA == nil || !userMFA.Confirmed {
+		return nil
+	}
+
+	challengeToken := a.tokenStrategy.GenerateToken()
+	expiredAt := time.Now().Add(mfaChallengeTTL)
+	err = a.putJSON(mfaChallengePrefix+challengeToken, mfaChallenge{
+		UserID:     user.ID,
+		Identifier: params.Identifier,
+		IPAddress:  params.IPAddress,
+		UserAgent:  params.UserAgent,
+		ExpiredAt:  expiredAt,
+	}, mfaChallengeTTL)
+	if err != nil {
+		return err
+	}
+	return &ErrMFARequired{
+		ChallengeToken: challengeToken,
+		ExpiredAt:      expiredAt,
+	}
+}
+
+// verifyMFACode will validate TOTP code or recovery code of user
+// the used TOTP time step and recovery code can't be used anymore
+func (a *Auth) verifyMFACode(userMFA *schema.UserMFA, code string) (bool, error) {
+	if step, ok := mfa.ValidateCode(userMFA.Secret, code, time.Now(), mfa.DefaultSkew); ok {
+		return userMFA.UseTimeStep(step)
+	}
+	return userMFA.UseRecoveryCode(mfa.HashRecoveryCode(code))
+}
+
+// loginParams will return the login params of challenge that used by throttling
+func (c *mfaChallenge) loginParams() LoginParams {
+	return LoginParams{
+		Identifier: c.Identifier,
+		IPAddress:  c.IPAddress,
+		UserAgent:  c.UserAgent,
+	}
+}
+
+// completeMFA will verify the code of mfa challenge and return the user
+// the challenge is deleted after successful verification or too many failed attempts.
+// if throttling is enabled, the failed code is counted in the failed attempts of identifier,
+// and the failed attempts are reset after successful verification
+func (a *Auth) completeMFA(challengeToken, code string) (*schema.User, *mfaChallenge, error) {
+	key := mfaChallengePrefix + challengeToken
+	var challenge mfaChallenge
+	err := a.getJSON(key, &challenge)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, nil, ErrInvalidMFAChallenge
+		}
+		return nil, nil, err
+	}
+
+	if a.throttle.enabled() {
+		err = a.checkThrottle(challenge.loginParams())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": challenge.UserID,
+	})
+	if err != nil || user == nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if !user.Active {
+		return nil, nil, ErrUserNotActive
+	}
+
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userMFA == nil || !userMFA.Confirmed {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	valid, err := a.verifyMFACode(userMFA, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		var lockErr error
+		if a.throttle.enabled() {
+			lockErr = a.recordFailedLogin(challenge.loginParams())
+		}
+
+		challenge.Attempts++
+		if challenge.Attempts >= mfaMaxAttempts || lockErr != nil {
+			err = a.sessionStore.Delete(key)
+		} else {
+			err = a.putJSON(key, challenge, time.Until(challenge.ExpiredAt))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if lockErr != nil {
+			return nil, nil, lockErr
+		}
+		return nil, nil, ErrInvalidMFACode
+	}
+
+	err = a.sessionStore.Delete(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = a.resetFailedLogin(challenge.loginParams())
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &challenge, nil
+}
+
+// VerifyMFA will complete the login that returned *ErrMFARequired and return token string for authentication based token
+// code can be TOTP code or recovery code
+func (a *Auth) VerifyMFA(challengeToken, code string) (*schema.User, string, error) {
+	user, challenge, err := a.completeMFA(challengeToken, code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := a.newSession(user, sessionParams{
+		ipAddress: challenge.IPAddress,
+		userAgent: challenge.UserAgent,
+	})
+	if err != nil {
+		return nil, "", ErrCreatingToken
+	}
+	return user, token, nil
+}
+
+// VerifyMFACookie will complete the login that returned *ErrMFARequired and set the cookie with validated user session
+// code can be TOTP code or recovery code
+func (a *Auth) VerifyMFACookie(w http.ResponseWriter, challengeToken, code string) (*schema.User, error) {
+	user, challenge, err := a.completeMFA(challengeToken, code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.setSessionCookie(w, user, sessionParams{
+		isCookie:  true,
+		ipAddress: challenge.IPAddress,
+		userAgent: challenge.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// VerifyMFAWithRefresh will complete the login that returned *ErrMFARequired and return access token and refresh token
+// code can be TOTP code or recovery code
+func (a *Auth) VerifyMFAWithRefresh(challengeToken, code string) (*schema.User, *TokenPair, error) {
+	if a.refreshInSeconds <= 0 {
+		return nil, nil, ErrRefreshDisabled
+	}
+
+	user, challenge, err := a.completeMFA(challengeToken, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := a.issueTokenPair(newFamilyID(), &refreshFamily{
+		IPAddress: challenge.IPAddress,
+		UserAgent: challenge.UserAgent,
+	}, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// EnrollMFA will generate a new TOTP secret for user
+// mfa isn't enabled until it's confirmed by ConfirmMFA with the code from authenticator app
+func (a *Auth) EnrollMFA(user *schema.User) (*MFAEnrollment, error) {
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA != nil && userMFA.Confirmed {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if userMFA == nil {
+		userMFA = a.dbSchema.UserMFA(&schema.UserMFA{UserID: user.ID})
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	userMFA.Secret = secret
+	userMFA.LastUsedStep = 0
+	err = userMFA.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return &MFAEnrollment{
+		Secret: secret,
+		URI:    mfa.KeyURI(a.mfaIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmMFA will enable mfa of user when the TOTP code is valid
+// It'll return the recovery codes, the codes are only stored as hash so it must be shown to user at this time
+func (a *Auth) ConfirmMFA(user *schema.User, code string) ([]string, error) {
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if userMFA.Confirmed {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := mfa.ValidateCode(userMFA.Secret, code, time.Now(), mfa.DefaultSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	valid, err := userMFA.UseTimeStep(step)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidMFACode
+	}
+
+	userMFA.Confirmed = true
+	err = userMFA.Save()
+	if err != nil {
+		return nil, err
+	}
+	return a.setRecoveryCodes(userMFA)
+}
+
+// RegenerateRecoveryCodes will replace the recovery codes of user with the new one
+func (a *Auth) RegenerateRecoveryCodes(user *schema.User) ([]string, error) {
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA == nil || !userMFA.Confirmed {
+		return nil, ErrMFANotEnrolled
+	}
+	return a.setRecoveryCodes(userMFA)
+}
+
+// setRecoveryCodes will generate the recovery codes and store the hashed codes
+func (a *Auth) setRecoveryCodes(userMFA *schema.UserMFA) ([]string, error) {
+	codes, err := mfa.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, mfa.HashRecoveryCode(code))
+	}
+
+	err = userMFA.SetRecoveryCodes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA will disable mfa of user and delete all recovery codes
+func (a *Auth) DisableMFA(user *schema.User) error {
+	userMFA, err := a.dbSchema.UserMFA(nil).FindUserMFA(user.ID)
+	if err != nil {
+		return err
+	}
+	if userMFA == nil {
+		return ErrMFANotEnrolled
+	}
+	return userMFA.Delete()
+}
@@ -0,0 +1,151 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the length of TOTP code
+	Digits = 6
+
+	// Period is the time step of TOTP code
+	Period = 30 * time.Second
+
+	// DefaultSkew is the number of time steps before and after the current time step that still accepted
+	DefaultSkew = 1
+
+	secretSize       = 20
+	recoveryCodeSize = 5
+)
+
+var (
+	ErrInvalidSecret = errors.New("invalid totp secret")
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret will generate a new random TOTP secret as base32 string
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// decodeSecret is helper func to decode the base32 secret, the padding and spaces are ignored
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	secret = strings.TrimRight(secret, "=")
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// TimeStep will return the TOTP time step of t
+func TimeStep(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// generateCode will generate HOTP code of the counter as described in RFC 4226
+func generateCode(key []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
+
+// GenerateCode will generate TOTP code of the secret at time t as described in RFC 6238
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return generateCode(key, TimeStep(t)), nil
+}
+
+// ValidateCode will validate the TOTP code at time t, the codes within skew time steps are accepted
+// the matched time step is returned, so it can be stored to prevent the code from being reused
+func ValidateCode(secret, code string, t time.Time, skew int) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+
+	current := TimeStep(t)
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+		expected := generateCode(key, step)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// KeyURI will return `otpauth://` uri of the secret, it's usually rendered as QR code for authenticator app
+func KeyURI(issuer, account, secret string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", Digits))
+	params.Set("period", fmt.Sprintf("%d", int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// GenerateRecoveryCodes will generate n random recovery codes with format `xxxxx-xxxxx`
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, recoveryCodeSize)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes = append(codes, code[:recoveryCodeSize]+"-"+code[recoveryCodeSize:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode will hash the recovery code, so the plain code doesn't need to be stored
+// the code is normalized before hashed, so the dash and letter case are ignored
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
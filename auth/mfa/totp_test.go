@@ -0,0 +1,154 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of RFC 6238 Appendix B ("12345678901234567890") as base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfcVectors are the SHA1 test vectors of RFC 6238 Appendix B,
+// the 8 digits codes of RFC are truncated to the last 6 digits
+var rfcVectors = []struct {
+	unix int64
+	step int64
+	code string
+}{
+	{59, 0x1, "287082"},                 // 94287082
+	{1111111109, 0x23523EC, "081804"},   // 07081804
+	{1111111111, 0x23523ED, "050471"},   // 14050471
+	{1234567890, 0x273EF07, "005924"},   // 89005924
+	{2000000000, 0x3F940AA, "279037"},   // 69279037
+	{20000000000, 0x27BC86AA, "353130"}, // 65353130
+}
+
+func TestGenerateCodeRFC6238Vectors(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+
+	for _, v := range rfcVectors {
+		at := time.Unix(v.unix, 0)
+		if step := TimeStep(at); step != v.step {
+			t.Fatalf("time step at %d: expected %X, got %X", v.unix, v.step, step)
+		}
+		if code := generateCode(key, v.step); code != v.code {
+			t.Fatalf("code at %d: expected %s, got %s", v.unix, v.code, code)
+		}
+
+		code, err := GenerateCode(rfcSecret, at)
+		if err != nil {
+			t.Fatalf("failed to generate code at %d: %v", v.unix, err)
+		}
+		if code != v.code {
+			t.Fatalf("code at %d: expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+}
+
+func TestValidateCodeRFC6238Vectors(t *testing.T) {
+	for _, v := range rfcVectors {
+		step, ok := ValidateCode(rfcSecret, v.code, time.Unix(v.unix, 0), 0)
+		if !ok {
+			t.Fatalf("code at %d should be valid", v.unix)
+		}
+		if step != v.step {
+			t.Fatalf("matched step at %d: expected %X, got %X", v.unix, v.step, step)
+		}
+	}
+
+	// the secret is normalized, so the lowercase, spaces and padding are accepted
+	if _, ok := ValidateCode("gezd gnbv gy3t qojq gezd gnbv gy3t qojq===", "050471", time.Unix(1111111111, 0), 0); !ok {
+		t.Fatalf("code of normalized secret should be valid")
+	}
+}
+
+func TestValidateCodeSkew(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+	now := time.Unix(1111111111, 0)
+	current := TimeStep(now)
+
+	cases := []struct {
+		name   string
+		offset int64
+		skew   int
+		valid  bool
+	}{
+		{"current step without skew", 0, 0, true},
+		{"previous step without skew", -1, 0, false},
+		{"next step without skew", 1, 0, false},
+		{"previous step within default skew", -1, DefaultSkew, true},
+		{"next step within default skew", 1, DefaultSkew, true},
+		{"two steps before default skew", -2, DefaultSkew, false},
+		{"two steps after default skew", 2, DefaultSkew, false},
+		{"two steps before wider skew", -2, 2, true},
+	}
+
+	for _, c := range cases {
+		code := generateCode(key, current+c.offset)
+		step, ok := ValidateCode(rfcSecret, code, now, c.skew)
+		if ok != c.valid {
+			t.Fatalf("%s: expected valid %v, got %v", c.name, c.valid, ok)
+		}
+		if ok && step != current+c.offset {
+			t.Fatalf("%s: expected matched step %d, got %d", c.name, current+c.offset, step)
+		}
+	}
+}
+
+func TestValidateCodeReturnsSameStepOnReuse(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+	issued := time.Unix(1111111111, 0)
+	code := generateCode(key, TimeStep(issued))
+
+	// the code replayed in the next period is still within skew, the same step is returned,
+	// so the stored step of the first use rejects it
+	first, ok := ValidateCode(rfcSecret, code, issued, DefaultSkew)
+	if !ok {
+		t.Fatalf("code should be valid when issued")
+	}
+	replayed, ok := ValidateCode(rfcSecret, code, issued.Add(Period), DefaultSkew)
+	if !ok {
+		t.Fatalf("replayed code should be matched within skew")
+	}
+	if replayed != first {
+		t.Fatalf("replayed code should match step %d, got %d", first, replayed)
+	}
+
+	// the next code has a later step, so it's not rejected as reuse
+	next, ok := ValidateCode(rfcSecret, generateCode(key, first+1), issued.Add(Period), DefaultSkew)
+	if !ok || next <= first {
+		t.Fatalf("next code should match a later step than %d, got %d (valid %v)", first, next, ok)
+	}
+}
+
+func TestValidateCodeRejectsInvalidInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	// the invalid secret, wrong length and wrong code are rejected
+	cases := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"invalid secret", "not-base32!", "050471"},
+		{"empty secret", "", "050471"},
+		{"short code", rfcSecret, "05047"},
+		{"eight digits code", rfcSecret, "14050471"},
+		{"wrong code", rfcSecret, "050472"},
+	}
+
+	for _, c := range cases {
+		if _, ok := ValidateCode(c.secret, c.code, now, DefaultSkew); ok {
+			t.Fatalf("%s: code should be rejected", c.name)
+		}
+	}
+}
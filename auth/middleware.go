@@ -0,0 +1,361 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+// CredentialSource is the source of credential that accepted by Middleware
+type CredentialSource int
+
+const (
+	// CredentialHeader is the session token in header `Authorization: Bearer <token>`
+	CredentialHeader CredentialSource = iota
+	// CredentialCookie is the session cookie, the csrf token of unsafe request is validated if csrf protection is enabled
+	CredentialCookie
+	// CredentialAPIKey is the api key in header `Authorization: ApiKey <key>`
+	CredentialAPIKey
+	// CredentialQuery is the session token in query param, e.g. `?access_token=<token>`
+	CredentialQuery
+
+	defaultQueryParam = "access_token"
+)
+
+// middlewareOptions contains the configuration of Middleware
+type middlewareOptions struct {
+	sources     []CredentialSource
+	queryParam  string
+	rbac        bool
+	rules       bool
+	optional    bool
+	permissions []string
+	roles       []string
+	objects     []objectRequirement
+	domain      DomainResolver
+}
+
+// MiddlewareOption is used to configure Middleware
+type MiddlewareOption func(opts *middlewareOptions)
+
+// WithCredentialSources will set the accepted credential sources, the first credential found in the request is used.
+// the default sources are CredentialHeader and CredentialAPIKey
+func WithCredentialSources(sources ...CredentialSource) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.sources = sources
+	}
+}
+
+// WithQueryParam will set the query param name of CredentialQuery, default is `access_token`
+func WithQueryParam(name string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.queryParam = name
+	}
+}
+
+// WithRBAC will enable or disable the permission check by request method and route,
+// the rules of permission and roles are executed when rule evaluation is enabled
+func WithRBAC(enabled bool) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.rbac = enabled
+	}
+}
+
+// WithRules will enable or disable the rule evaluation of the permission that matched by request method and route
+func WithRules(enabled bool) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.rules = enabled
+	}
+}
+
+// WithOptionalAuth will let the request without credential pass as anonymous request,
+// the request with invalid credential is still rejected
+func WithOptionalAuth(enabled bool) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.optional = enabled
+	}
+}
+
+// RequirePermissions will reject the request if user doesn't have all the permissions
+func RequirePermissions(names ...string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.permissions = append(opts.permissions, names...)
+	}
+}
+
+// RequireRoles will reject the request if user doesn't have all the roles
+func RequireRoles(names ...string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.roles = append(opts.roles, names...)
+	}
+}
+
+// WithDomain will resolve the domain (tenant) of request, the request without domain is rejected.
+// the access of user is checked with the global roles and the roles assigned in the domain,
+// and the logged user of request is scoped to the domain, see schema.User.InDomain
+func WithDomain(resolver DomainResolver) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.domain = resolver
+	}
+}
+
+// hasSource will check the source is accepted
+func (o *middlewareOptions) hasSource(source CredentialSource) bool {
+	for _, s := range o.sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// requireUser will check the options need the logged user, so the anonymous request is rejected
+func (o *middlewareOptions) requireUser() bool {
+	return o.rbac || len(o.permissions) > 0 || len(o.roles) > 0 || len(o.objects) > 0
+}
+
+// Middleware will return the middleware that authenticate the request by the options
+// by default, it accepts the session token and api key from header Authorization without RBAC and rule evaluation.
+// the request that authenticated by api key is always checked against the api key scope.
+// the rejected request is written by ErrorHandler
+func (a *Auth) Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	options := &middlewareOptions{
+		sources:    []CredentialSource{CredentialHeader, CredentialAPIKey},
+		queryParam: defaultQueryParam,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	bearer := options.hasSource(CredentialHeader)
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, err := a.authenticateRequest(w, r, options)
+			if err != nil {
+				a.handleError(w, r, err, bearer)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticateRequest will authenticate and authorize the request by the options
+// the returned request contains the logged user, the current session id, the domain, and the path params of matched permission
+func (a *Auth) authenticateRequest(w http.ResponseWriter, r *http.Request, options *middlewareOptions) (*http.Request, error) {
+	var domain string
+	if options.domain != nil {
+		domain = options.domain(r)
+		if domain == "" {
+			return r, ErrMissingDomain
+		}
+		r = r.WithContext(context.WithValue(r.Context(), Domain, domain))
+	}
+
+	principal, source, err := a.getUserPrinciple(r, options)
+	if err != nil {
+		if err == ErrMissingCredential && options.optional && !options.requireUser() {
+			return r, nil
+		}
+		if source == CredentialCookie {
+			a.ClearSession(w, r)
+		}
+		return r, err
+	}
+
+	if source == CredentialCookie {
+		err = a.verifyCSRF(r, principal.session)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	ctx := r.Context()
+	if principal.session != nil {
+		ctx = context.WithValue(ctx, SessionID, principal.session.ID)
+		if principal.session.CSRFToken != "" {
+			ctx = context.WithValue(ctx, CSRFToken, principal.session.CSRFToken)
+		}
+	}
+	if principal.apiKey != nil {
+		ctx = context.WithValue(ctx, APIKeyPrinciple, principal.apiKey)
+	}
+	if principal.impersonator != nil {
+		ctx = context.WithValue(ctx, ImpersonatorPrinciple, principal.impersonator)
+	}
+	if domain != "" {
+		principal.user = principal.user.InDomain(domain)
+	}
+	r = r.WithContext(ctx)
+
+	// the scope of api key is always checked, even if RBAC and rule evaluation are disabled
+	if options.rbac || options.rules || principal.apiKey != nil {
+		r, err = a.executeRule(r, principal.user, options.rbac, options.rules)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	err = a.checkRequirements(r, principal, options)
+	if err != nil {
+		return r, err
+	}
+
+	ctx = context.WithValue(r.Context(), UserPrinciple, principal.user)
+	return r.WithContext(ctx), nil
+}
+
+// checkRequirements will check the required permissions, roles and object actions of logged user
+// api key can only pass the required permissions in its scope, and it can't pass the required object actions
+func (a *Auth) checkRequirements(r *http.Request, principal *principal, options *middlewareOptions) error {
+	ctx := r.Context()
+	user := a.dbSchema.User(principal.user)
+	for _, name := range options.permissions {
+		allowed, err := user.HasPermissionContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrPermissionDenied
+		}
+
+		if principal.apiKey != nil {
+			permission, err := a.dbSchema.Permission(nil).GetPermissionContext(ctx, name)
+			if err != nil {
+				return err
+			}
+			if permission == nil {
+				return ErrPermissionDenied
+			}
+			allowed, err = principal.apiKey.HasPermissionContext(ctx, permission)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return ErrPermissionDenied
+			}
+		}
+	}
+
+	for _, name := range options.roles {
+		allowed, err := user.HasRoleContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrPermissionDenied
+		}
+	}
+
+	// the object grants aren't in the api key scope
+	if len(options.objects) > 0 && principal.apiKey != nil {
+		return ErrPermissionDenied
+	}
+
+	params := GetPathParams(r)
+	for _, object := range options.objects {
+		id := params[object.param]
+		if id == "" {
+			return ErrPermissionDenied
+		}
+		allowed, err := a.CanOnObject(ctx, user, object.action, object.resourceType, id)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+// getCredential will get the credential of source from the request
+// empty credential is returned when the request doesn't contain the credential of source
+func (a *Auth) getCredential(r *http.Request, source CredentialSource, options *middlewareOptions) (string, error) {
+	switch source {
+	case CredentialCookie:
+		if _, err := r.Cookie(a.cookieName()); err == http.ErrNoCookie {
+			return "", nil
+		}
+		return a.getSessionCookie(r)
+	case CredentialHeader, CredentialAPIKey:
+		if r.Header.Get(authorization) == "" {
+			return "", nil
+		}
+		scheme, credential, err := getAuthorization(r)
+		if err != nil {
+			return "", err
+		}
+		// the api key scheme is only accepted by CredentialAPIKey, and the other schemes are accepted by CredentialHeader
+		if strings.EqualFold(scheme, apiKeyScheme) != (source == CredentialAPIKey) {
+			return "", nil
+		}
+		return credential, nil
+	case CredentialQuery:
+		return r.URL.Query().Get(options.queryParam), nil
+	}
+	return "", nil
+}
+
+// getUserPrinciple is non exported helper function to get logged user by http request and the accepted credential sources
+// the source of the first credential found in the request is returned.
+// the returned error is classified by newAuthError, e.g. ErrMissingCredential or ErrSessionExpired
+func (a *Auth) getUserPrinciple(r *http.Request, options *middlewareOptions) (*principal, CredentialSource, error) {
+	for _, source := range options.sources {
+		credential, err := a.getCredential(r, source, options)
+		if err != nil {
+			return nil, source, err
+		}
+		if credential == "" {
+			continue
+		}
+
+		if source == CredentialAPIKey {
+			user, apiKey, err := a.VerifyAPIKey(credential)
+			if err != nil {
+				return nil, source, err
+			}
+			return &principal{user: user, apiKey: apiKey}, source, nil
+		}
+		principal, err := a.getSessionPrinciple(credential)
+		return principal, source, err
+	}
+	return nil, -1, ErrMissingCredential
+}
+
+// getSessionPrinciple will get logged user by session token
+// the impersonated session is rejected if the impersonator isn't allowed to impersonate anymore
+func (a *Auth) getSessionPrinciple(token string) (*principal, error) {
+	record, err := a.verifySession(token)
+	if err != nil {
+		return nil, ErrSessionExpired
+	}
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": record.UserID,
+	})
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.Active {
+		return nil, ErrUserNotActive
+	}
+
+	result := &principal{user: user, session: record}
+	if record.ImpersonatorID > 0 {
+		result.impersonator, err = a.verifyImpersonator(record)
+		if err != nil {
+			return nil, ErrImpersonationForbidden
+		}
+	}
+	return result, nil
+}
+
+// principal is the authenticated user of request with the session or api key that used to authenticate
+type principal struct {
+	user         *schema.User
+	session      *sessionRecord
+	apiKey       *schema.APIKey
+	impersonator *schema.User
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrNoNotifier = errors.New("notifier is not provided")
+)
+
+// NotificationType is the type of notification that sent by Notifier
+type NotificationType string
+
+const (
+	NotificationPasswordReset     NotificationType = "password_reset"
+	NotificationEmailVerification NotificationType = "email_verification"
+)
+
+// Notification contains the token that should be delivered to user, e.g. as link in the email
+type Notification struct {
+	Type      NotificationType
+	User      *schema.User
+	Token     string
+	ExpiredAt time.Time
+}
+
+// Notifier is used to deliver the notification to user, e.g. by email or sms
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+// NotifierFunc is an adapter to allow the use of ordinary function as Notifier
+type NotifierFunc func(notification Notification) error
+
+// Notify will call f(notification)
+func (f NotifierFunc) Notify(notification Notification) error {
+	return f(notification)
+}
@@ -0,0 +1,228 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// Audience is the `aud` claim of id token, it can be a string or array of string
+type Audience []string
+
+// UnmarshalJSON will decode the audience from string or array of string
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	err := json.Unmarshal(data, &multiple)
+	if err != nil {
+		return err
+	}
+	*a = Audience(multiple)
+	return nil
+}
+
+// contains will check the audience contains the client id or not
+func (a Audience) contains(clientID string) bool {
+	for _, audience := range a {
+		if audience == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// IDToken contains the validated claims of id token
+type IDToken struct {
+	Issuer          string
+	Subject         string
+	Audience        Audience
+	AuthorizedParty string
+	Nonce           string
+	IssuedAt        int64
+	ExpiresAt       int64
+
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// ExpiredTime will return the expiration time of id token
+func (t *IDToken) ExpiredTime() time.Time {
+	return time.Unix(t.ExpiresAt, 0)
+}
+
+// idTokenClaims is the raw claims of id token
+// some providers send `email_verified` as string, so it's decoded as raw json
+type idTokenClaims struct {
+	Issuer          string          `json:"iss"`
+	Subject         string          `json:"sub"`
+	Audience        Audience        `json:"aud"`
+	AuthorizedParty string          `json:"azp"`
+	Nonce           string          `json:"nonce"`
+	IssuedAt        int64           `json:"iat"`
+	ExpiresAt       int64           `json:"exp"`
+	Email           string          `json:"email"`
+	EmailVerified   json.RawMessage `json:"email_verified"`
+	Name            string          `json:"name"`
+}
+
+// toIDToken will convert the raw claims to IDToken
+func (c *idTokenClaims) toIDToken() *IDToken {
+	verified := strings.Trim(string(c.EmailVerified), `"`)
+	return &IDToken{
+		Issuer:          c.Issuer,
+		Subject:         c.Subject,
+		Audience:        c.Audience,
+		AuthorizedParty: c.AuthorizedParty,
+		Nonce:           c.Nonce,
+		IssuedAt:        c.IssuedAt,
+		ExpiresAt:       c.ExpiresAt,
+		Email:           c.Email,
+		EmailVerified:   verified == "true",
+		Name:            c.Name,
+	}
+}
+
+// JSONWebKey represents a public key in the JWKS document
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet represents the JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// publicKey is the parsed signing key of provider
+type publicKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// verify will verify the signature of signing input
+// the algorithm is pinned by the key type to prevent algorithm confusion
+func (k publicKey) verify(algorithm string, input, signature []byte) bool {
+	if algorithm != k.algorithm {
+		return false
+	}
+	digest := sha256.Sum256(input)
+	switch key := k.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	}
+	return false
+}
+
+// NewRSAKey will create JSONWebKey of RSA public key
+func NewRSAKey(keyID string, key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		KeyID:     keyID,
+		Use:       "sig",
+		Algorithm: AlgorithmRS256,
+		N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// parse will parse the public key, only RS256 and ES256 signing key are supported
+func (k *JSONWebKey) parse() (publicKey, bool) {
+	if k.Use != "" && k.Use != "sig" {
+		return publicKey{}, false
+	}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return publicKey{}, false
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return publicKey{}, false
+		}
+		return publicKey{
+			algorithm: AlgorithmRS256,
+			key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		}, true
+	case "EC":
+		if k.Curve != "P-256" {
+			return publicKey{}, false
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return publicKey{}, false
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return publicKey{}, false
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, false
+		}
+		return publicKey{algorithm: AlgorithmES256, key: key}, true
+	}
+	return publicKey{}, false
+}
+
+// fetchKeys will fetch the signing keys from JWKS uri
+func fetchKeys(ctx context.Context, client *http.Client, jwksURI string) (map[string]publicKey, error) {
+	var keySet JSONWebKeySet
+	err := getJSON(ctx, client, jwksURI, &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]publicKey)
+	for _, jwk := range keySet.Keys {
+		if key, ok := jwk.parse(); ok {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,351 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	defaultLeeway = time.Minute
+)
+
+var (
+	ErrDiscovery       = errors.New("error while fetching openid configuration")
+	ErrInvalidIssuer   = errors.New("issuer of openid configuration doesn't match")
+	ErrExchange        = errors.New("error while exchanging authorization code")
+	ErrNoIDToken       = errors.New("id token is not returned by provider")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+	ErrIDTokenExpired  = errors.New("id token is expired")
+	ErrInvalidAudience = errors.New("invalid id token audience")
+	ErrInvalidNonce    = errors.New("invalid id token nonce")
+	ErrUnknownKey      = errors.New("unknown id token signing key")
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Config contains configuration of OpenID Connect provider
+// Name is used to identify the provider, e.g. `google`, it's stored as provider of user identity
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	// Scopes is the requested scopes, default is `openid email profile`
+	Scopes []string
+
+	// HTTPClient is used to call the provider, default is http.DefaultClient
+	HTTPClient *http.Client
+
+	// Leeway is the tolerance of clock skew when validating id token, default is 1 minute
+	Leeway time.Duration
+}
+
+// Metadata represents the openid configuration of provider that fetched by discovery
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Token represents the token response of provider
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Provider is OpenID Connect relying party of one provider
+// It'll build the authorization url, exchange the authorization code, and validate the id token
+type Provider struct {
+	config   Config
+	metadata Metadata
+	client   *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]publicKey
+}
+
+// Discover will fetch the openid configuration of issuer
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Metadata, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var metadata Metadata
+	err := getJSON(ctx, client, strings.TrimSuffix(issuer, "/")+discoveryPath, &metadata)
+	if err != nil {
+		return nil, ErrDiscovery
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, ErrInvalidIssuer
+	}
+	return &metadata, nil
+}
+
+// NewProvider acts as constructor with the provider configuration
+// It'll fetch the openid configuration of issuer by discovery
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	metadata, err := Discover(ctx, client, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	return NewProviderWithMetadata(config, *metadata), nil
+}
+
+// NewProviderWithMetadata acts as constructor with the provider configuration and metadata
+// It can be used when the provider doesn't support discovery
+func NewProviderWithMetadata(config Config, metadata Metadata) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+	if config.Leeway <= 0 {
+		config.Leeway = defaultLeeway
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{
+		config:   config,
+		metadata: metadata,
+		client:   client,
+		keys:     make(map[string]publicKey),
+	}
+}
+
+// Name will return the name of provider
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// Metadata will return the openid configuration of provider
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// AuthCodeURL will return the authorization url with state, nonce and PKCE code challenge
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange will exchange the authorization code with the token by PKCE code verifier
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("client_id", p.config.ClientID)
+	params.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v: %s", ErrExchange, strings.TrimSpace(string(body)))
+	}
+
+	var token Token
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return nil, ErrExchange
+	}
+	if token.IDToken == "" {
+		return nil, ErrNoIDToken
+	}
+	return &token, nil
+}
+
+// VerifyIDToken will validate the signature, issuer, audience, expiration and nonce of id token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	key, err := p.getKey(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if !key.verify(header.Algorithm, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidIDToken
+	}
+
+	var claims idTokenClaims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	idToken := claims.toIDToken()
+
+	if strings.TrimSuffix(idToken.Issuer, "/") != strings.TrimSuffix(p.metadata.Issuer, "/") {
+		return nil, ErrInvalidIDToken
+	}
+	if !idToken.Audience.contains(p.config.ClientID) {
+		return nil, ErrInvalidAudience
+	}
+	if len(idToken.Audience) > 1 && idToken.AuthorizedParty != p.config.ClientID {
+		return nil, ErrInvalidAudience
+	}
+	if time.Now().After(idToken.ExpiredTime().Add(p.config.Leeway)) {
+		return nil, ErrIDTokenExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidNonce
+	}
+	return idToken, nil
+}
+
+// getKey will return the signing key by key id, the keys will be fetched again if the key is unknown
+func (p *Provider) getKey(ctx context.Context, keyID string) (publicKey, error) {
+	p.mu.RLock()
+	key, ok := p.findKey(keyID)
+	p.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := fetchKeys(ctx, p.client, p.metadata.JWKSURI)
+	if err != nil {
+		return publicKey{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	key, ok = p.findKey(keyID)
+	if !ok {
+		return publicKey{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// findKey will find the key by key id, the only key is used if the id token doesn't have `kid` header
+func (p *Provider) findKey(keyID string) (publicKey, bool) {
+	if keyID == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[keyID]
+	return key, ok
+}
+
+// GenerateState will generate random string that used as state or nonce
+func GenerateState() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateCodeVerifier will generate PKCE code verifier as described in RFC 7636
+func GenerateCodeVerifier() (string, error) {
+	return GenerateState()
+}
+
+// CodeChallenge will return S256 code challenge of the code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getJSON is helper func to get json response from url
+func getJSON(ctx context.Context, client *http.Client, url string, value interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// decodeSegment is helper func to decode base64url json segment
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
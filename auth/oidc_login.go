@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/oidc"
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("oidc provider is not registered")
+	ErrInvalidOIDCState     = errors.New("invalid or expired oidc state")
+	ErrIdentityNotLinked    = errors.New("external identity is not linked to any user")
+	ErrIdentityAlreadyUsed  = errors.New("external identity is already linked to another user")
+	ErrOIDCAccessDenied     = errors.New("oidc authorization is denied by provider")
+	ErrOIDCLinkUserMismatch = errors.New("oidc link is not completed by the user that started it")
+)
+
+const (
+	oidcStatePrefix       = "guardian:oidc_state:"
+	oidcStateTTL          = 10 * time.Minute
+	oidcStateCookieSuffix = "_oidc_state"
+)
+
+// oidcState represents the pending oidc login that waiting for provider callback
+// LinkUserID is set when the login is started by BeginOIDCLink
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	LinkUserID   int64  `json:"link_user_id"`
+}
+
+// RegisterOIDCProvider will register OpenID Connect provider in the auth module
+// the provider is identified by the name of provider configuration
+func (a *Auth) RegisterOIDCProvider(provider *oidc.Provider) {
+	if provider != nil {
+		a.oidcProviders[provider.Name()] = provider
+	}
+}
+
+// BeginOIDCLogin will return the authorization url of provider, user should be redirected to this url
+// the state, nonce and PKCE code verifier are stored in the session store until the callback is received.
+// the hash of state is set as cookie, so the callback can only be completed by the same browser
+func (a *Auth) BeginOIDCLogin(w http.ResponseWriter, providerName string) (string, error) {
+	return a.beginOIDC(w, providerName, 0)
+}
+
+// BeginOIDCLink will return the authorization url of provider to link the external identity to the logged user
+// the identity is linked when the callback is completed by CompleteOIDCLogin or CompleteOIDCLoginCookie,
+// the callback request must be authenticated as the same user
+func (a *Auth) BeginOIDCLink(w http.ResponseWriter, user *schema.User, providerName string) (string, error) {
+	if user == nil {
+		return "", ErrInvalidUserLogin
+	}
+	return a.beginOIDC(w, providerName, user.ID)
+}
+
+// oidcStateCookie will create the cookie that binds the oidc state to the browser
+// the cookie must be sent on the redirect from provider, so it's always lax
+func (a *Auth) oidcStateCookie(value string) *http.Cookie {
+	cookie := a.newCookie(value)
+	cookie.Name = a.cookieName() + oidcStateCookieSuffix
+	cookie.HttpOnly = true
+	cookie.SameSite = http.SameSiteLaxMode
+	return cookie
+}
+
+// verifyOIDCStateCookie will check the state of callback is started by the same browser
+func (a *Auth) verifyOIDCStateCookie(r *http.Request, stateToken string) bool {
+	cookie, err := r.Cookie(a.cookieName() + oidcStateCookieSuffix)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashToken(stateToken))) == 1
+}
+
+// beginOIDC will store the oidc state, bind it to the browser and build the authorization url
+func (a *Auth) beginOIDC(w http.ResponseWriter, providerName string, linkUserID int64) (string, error) {
+	provider, ok := a.oidcProviders[providerName]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	state, err := oidc.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	err = a.putJSON(oidcStatePrefix+state, oidcState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		LinkUserID:   linkUserID,
+	}, oidcStateTTL)
+	if err != nil {
+		return "", err
+	}
+
+	cookie := a.oidcStateCookie(hashToken(state))
+	cookie.MaxAge = int(oidcStateTTL.Seconds())
+	cookie.Expires = time.Now().Add(oidcStateTTL)
+	http.SetCookie(w, cookie)
+	return provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(codeVerifier)), nil
+}
+
+// completeOIDC will validate the callback request, exchange the authorization code and return the linked user
+// the state can only be used once, and only by the browser that has the state cookie.
+// the link callback must be authenticated as the user that started the link
+func (a *Auth) completeOIDC(r *http.Request) (*schema.User, error) {
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		return nil, ErrOIDCAccessDenied
+	}
+	stateToken, code := query.Get("state"), query.Get("code")
+	if stateToken == "" || code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	if !a.verifyOIDCStateCookie(r, stateToken) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	data, err := a.sessionStore.Take(oidcStatePrefix + stateToken)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+	var state oidcState
+	err = json.Unmarshal([]byte(data), &state)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.LinkUserID > 0 {
+		userID, err := a.requestUserID(r)
+		if err != nil {
+			return nil, err
+		}
+		if userID != state.LinkUserID {
+			return nil, ErrOIDCLinkUserMismatch
+		}
+	}
+
+	provider, ok := a.oidcProviders[state.Provider]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+	token, err := provider.Exchange(r.Context(), code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := provider.VerifyIDToken(r.Context(), token.IDToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.resolveIdentity(provider.Name(), idToken, state.LinkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrUserNotActive
+	}
+	return user, nil
+}
+
+// requestUserID will return the id of user that authenticated the request
+// the user is taken from the middleware, or from the session cookie if the callback route isn't protected by middleware.
+// 0 will be returned if the request isn't authenticated
+func (a *Auth) requestUserID(r *http.Request) (int64, error) {
+	if user := GetUserLogin(r); user != nil {
+		return user.ID, nil
+	}
+
+	sessionToken, err := a.getSessionCookie(r)
+	if err != nil {
+		return 0, nil
+	}
+	record, err := a.lookupSession(sessionToken)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return record.UserID, nil
+}
+
+// resolveIdentity will find the user that linked to the external identity
+// if the identity isn't linked, it'll be linked to the user of BeginOIDCLink,
+// the user with the same verified email if OIDCLinkVerifiedEmail is enabled,
+// or a new user if OIDCAutoRegister is enabled
+func (a *Auth) resolveIdentity(provider string, idToken *oidc.IDToken, linkUserID int64) (*schema.User, error) {
+	identity, err := a.dbSchema.UserIdentity(nil).FindIdentity(provider, idToken.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if linkUserID > 0 && identity.UserID != linkUserID {
+			return nil, ErrIdentityAlreadyUsed
+		}
+		return a.findUserByID(identity.UserID)
+	}
+
+	var user *schema.User
+	switch {
+	case linkUserID > 0:
+		user, err = a.findUserByID(linkUserID)
+	case a.oidcLinkVerifiedEmail && idToken.EmailVerified && idToken.Email != "":
+		user, err = a.dbSchema.User(nil).FindUser(map[string]interface{}{
+			"email": idToken.Email,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		if !a.oidcAutoRegister {
+			return nil, ErrIdentityNotLinked
+		}
+		user, err = a.registerIdentityUser(idToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = a.dbSchema.UserIdentity(&schema.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+	}).CreateIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// registerIdentityUser will create a new user of external identity
+// the user has random username and password, so user can only login with the provider until the password is reset
+func (a *Auth) registerIdentityUser(idToken *oidc.IDToken) (*schema.User, error) {
+	if idToken.Email == "" {
+		return nil, ErrIdentityNotLinked
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	user := a.dbSchema.User(&schema.User{
+		Email:    idToken.Email,
+		Username: "user_" + hex.EncodeToString(b[:6]),
+	})
+	user.SetEncryptedPassword(a.passwordStrategy.HashPassword(hex.EncodeToString(b)))
+	err = user.CreateUser()
+	if err != nil {
+		return nil, err
+	}
+	a.publishRegistered(user)
+
+	if idToken.EmailVerified {
+		err = user.VerifyEmail()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// findUserByID is helper func to find user by id
+func (a *Auth) findUserByID(userID int64) (*schema.User, error) {
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// CompleteOIDCLogin will complete the oidc login with the callback request of provider and return token string for authentication based token
+// if user has enabled mfa, *ErrMFARequired will be returned and the login must be completed by VerifyMFA
+func (a *Auth) CompleteOIDCLogin(r *http.Request) (*schema.User, string, error) {
+	user, err := a.completeOIDC(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the failed mfa codes are counted by the email of user, the same as the login by email
+	params := requestSessionParams(r)
+	err = a.challengeMFA(user, LoginParams{
+		Identifier: user.Email,
+		IPAddress:  params.ipAddress,
+		UserAgent:  params.userAgent,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := a.newSession(user, params)
+	if err != nil {
+		return nil, "", ErrCreatingToken
+	}
+	return user, token, nil
+}
+
+// CompleteOIDCLoginCookie will complete the oidc login with the callback request of provider and set the cookie with validated user session
+// if user has enabled mfa, *ErrMFARequired will be returned and the login must be completed by VerifyMFACookie
+func (a *Auth) CompleteOIDCLoginCookie(w http.ResponseWriter, r *http.Request) (*schema.User, error) {
+	user, err := a.completeOIDC(r)
+	if err != nil {
+		return nil, err
+	}
+
+	params := requestSessionParams(r)
+	err = a.challengeMFA(user, LoginParams{
+		Identifier: user.Email,
+		IPAddress:  params.ipAddress,
+		UserAgent:  params.userAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params.isCookie = true
+	err = a.setSessionCookie(w, user, params)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// requestSessionParams will return the session metadata of request
+func requestSessionParams(r *http.Request) sessionParams {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+	return sessionParams{
+		ipAddress: ipAddress,
+		userAgent: r.UserAgent(),
+	}
+}
+
+// ListIdentities will return the external identities that linked to user
+func (a *Auth) ListIdentities(userID int64) ([]schema.UserIdentity, error) {
+	return a.dbSchema.UserIdentity(nil).GetUserIdentities(userID)
+}
+
+// UnlinkIdentity will remove the external identity of user
+func (a *Auth) UnlinkIdentity(userID int64, provider string) error {
+	identities, err := a.ListIdentities(userID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			return identity.Delete()
+		}
+	}
+	return schema.IdentityNotFound
+}
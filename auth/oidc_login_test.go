@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dhanarJkusuma/guardian/auth/oidc"
+	"github.com/dhanarJkusuma/guardian/auth/oidc/oidctest"
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/auth/token"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+const (
+	testProviderName = "oidctest"
+	testRedirectURL  = "https://app.example.com/oauth/callback"
+)
+
+// newOIDCTestAuth will start the mock provider and create the auth module that registers it by discovery
+// the mock provider must be closed by the caller
+func newOIDCTestAuth(t *testing.T) (*Auth, *oidctest.Server) {
+	server, err := oidctest.NewServer("client-id", "client-secret")
+	if err != nil {
+		t.Fatalf("failed to start mock provider: %v", err)
+	}
+
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Name:         testProviderName,
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to discover mock provider: %v", err)
+	}
+
+	a := NewAuth(Options{
+		SessionName:   "_guardian_test_",
+		SessionStore:  session.NewMemoryStore(0),
+		TokenStrategy: &token.DefaultTokenGenerator{},
+		OIDCProviders: []*oidc.Provider{provider},
+	})
+	return a, server
+}
+
+// beginTestLogin will start the oidc login and follow the authorization url like a browser
+// it returns the callback request that contains the state cookie
+func beginTestLogin(t *testing.T, a *Auth, server *oidctest.Server, link *schema.User) (*http.Request, string) {
+	recorder := httptest.NewRecorder()
+	var authURL string
+	var err error
+	if link != nil {
+		authURL, err = a.BeginOIDCLink(recorder, link, testProviderName)
+	} else {
+		authURL, err = a.BeginOIDCLogin(recorder, testProviderName)
+	}
+	if err != nil {
+		t.Fatalf("failed to begin oidc login: %v", err)
+	}
+
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	r := callbackRequest(state, code)
+	for _, cookie := range recorder.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	return r, state
+}
+
+// callbackRequest will create the callback request of provider without cookie
+func callbackRequest(state, code string) *http.Request {
+	query := url.Values{}
+	query.Set("state", state)
+	query.Set("code", code)
+	return httptest.NewRequest(http.MethodGet, testRedirectURL+"?"+query.Encode(), nil)
+}
+
+// updateTestState will change the stored oidc state, e.g. to simulate the nonce or verifier that doesn't match
+func updateTestState(t *testing.T, a *Auth, stateToken string, update func(state *oidcState)) {
+	var state oidcState
+	err := a.getJSON(oidcStatePrefix+stateToken, &state)
+	if err != nil {
+		t.Fatalf("failed to get oidc state: %v", err)
+	}
+	update(&state)
+	err = a.putJSON(oidcStatePrefix+stateToken, state, oidcStateTTL)
+	if err != nil {
+		t.Fatalf("failed to put oidc state: %v", err)
+	}
+}
+
+func TestOIDCProviderDiscoveryAndExchange(t *testing.T) {
+	a, server := newOIDCTestAuth(t)
+	defer server.Close()
+	provider := a.oidcProviders[testProviderName]
+
+	metadata := provider.Metadata()
+	if metadata.Issuer != server.Issuer() || metadata.TokenEndpoint != server.URL+"/token" {
+		t.Fatalf("unexpected discovered metadata: %+v", metadata)
+	}
+
+	state, _ := oidc.GenerateState()
+	nonce, _ := oidc.GenerateState()
+	verifier, _ := oidc.GenerateCodeVerifier()
+	code, returnedState, err := server.Authorize(provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(verifier)))
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	if returnedState != state {
+		t.Fatalf("state isn't returned to the callback, got %q", returnedState)
+	}
+
+	ctx := context.Background()
+	tok, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code with valid verifier: %v", err)
+	}
+	idToken, err := provider.VerifyIDToken(ctx, tok.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("failed to verify id token: %v", err)
+	}
+	if idToken.Subject != "oidctest-user" || idToken.Email != "oidctest@example.com" || !idToken.EmailVerified {
+		t.Fatalf("unexpected id token claims: %+v", idToken)
+	}
+
+	_, err = provider.VerifyIDToken(ctx, tok.IDToken, "other-nonce")
+	if err != oidc.ErrInvalidNonce {
+		t.Fatalf("expected ErrInvalidNonce, got %v", err)
+	}
+
+	// authorization code can only be exchanged once
+	_, err = provider.Exchange(ctx, code, verifier)
+	if err == nil || !strings.Contains(err.Error(), oidc.ErrExchange.Error()) {
+		t.Fatalf("expected exchange error for reused code, got %v", err)
+	}
+}
+
+func TestCompleteOIDCRejectsStateFromAnotherBrowser(t *testing.T) {
+	a, server := newOIDCTestAuth(t)
+	defer server.Close()
+	r, state := beginTestLogin(t, a, server, nil)
+
+	// the callback without state cookie
+	_, err := a.completeOIDC(callbackRequest(state, r.URL.Query().Get("code")))
+	if err != ErrInvalidOIDCState {
+		t.Fatalf("expected ErrInvalidOIDCState without cookie, got %v", err)
+	}
+
+	// the callback with state cookie of another login
+	other := callbackRequest(state, r.URL.Query().Get("code"))
+	other.AddCookie(a.oidcStateCookie(hashToken("another-state")))
+	_, err = a.completeOIDC(other)
+	if err != ErrInvalidOIDCState {
+		t.Fatalf("expected ErrInvalidOIDCState with another cookie, got %v", err)
+	}
+
+	// the rejected callback doesn't consume the state
+	_, err = a.sessionStore.Get(oidcStatePrefix + state)
+	if err != nil {
+		t.Fatalf("state is consumed by rejected callback: %v", err)
+	}
+}
+
+func TestCompleteOIDCRejectsNonceAndReplayedState(t *testing.T) {
+	a, server := newOIDCTestAuth(t)
+	defer server.Close()
+	r, state := beginTestLogin(t, a, server, nil)
+	updateTestState(t, a, state, func(s *oidcState) {
+		s.Nonce = "other-nonce"
+	})
+
+	// the code is exchanged with PKCE verifier, then the id token is rejected by nonce
+	_, err := a.completeOIDC(r)
+	if err != oidc.ErrInvalidNonce {
+		t.Fatalf("expected ErrInvalidNonce, got %v", err)
+	}
+
+	// the state can only be used once
+	_, err = a.completeOIDC(r)
+	if err != ErrInvalidOIDCState {
+		t.Fatalf("expected ErrInvalidOIDCState for replayed state, got %v", err)
+	}
+}
+
+func TestCompleteOIDCRejectsInvalidCodeVerifier(t *testing.T) {
+	a, server := newOIDCTestAuth(t)
+	defer server.Close()
+	r, state := beginTestLogin(t, a, server, nil)
+	updateTestState(t, a, state, func(s *oidcState) {
+		s.CodeVerifier, _ = oidc.GenerateCodeVerifier()
+	})
+
+	_, err := a.completeOIDC(r)
+	if err == nil || !strings.Contains(err.Error(), oidc.ErrExchange.Error()) {
+		t.Fatalf("expected exchange error for invalid code verifier, got %v", err)
+	}
+}
+
+func TestCompleteOIDCRejectsLinkByAnotherUser(t *testing.T) {
+	a, server := newOIDCTestAuth(t)
+	defer server.Close()
+
+	// the callback isn't authenticated
+	r, _ := beginTestLogin(t, a, server, &schema.User{ID: 7})
+	_, err := a.completeOIDC(r)
+	if err != ErrOIDCLinkUserMismatch {
+		t.Fatalf("expected ErrOIDCLinkUserMismatch without user, got %v", err)
+	}
+
+	// the callback is authenticated as another user
+	r, _ = beginTestLogin(t, a, server, &schema.User{ID: 7})
+	r = r.WithContext(context.WithValue(r.Context(), UserPrinciple, &schema.User{ID: 8}))
+	_, err = a.completeOIDC(r)
+	if err != ErrOIDCLinkUserMismatch {
+		t.Fatalf("expected ErrOIDCLinkUserMismatch with another user, got %v", err)
+	}
+}
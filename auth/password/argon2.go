@@ -0,0 +1,110 @@
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idID = "argon2id"
+
+// Argon2idPassword is PasswordGenerator that hash the password with Argon2id,
+// the hash is encoded in PHC string format, e.g. `$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>`.
+// the zero value of parameters will use the default value
+type Argon2idPassword struct {
+	// Memory is the memory cost in KiB, default is 64 MiB
+	Memory uint32
+
+	// Iterations is the time cost, default is 3
+	Iterations uint32
+
+	// Parallelism is the number of threads, default is 2
+	Parallelism uint8
+
+	// SaltLength and KeyLength are in bytes, default is 16 and 32
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// params will return the parameters with default value
+func (a *Argon2idPassword) params() Argon2idPassword {
+	params := *a
+	if params.Memory == 0 {
+		params.Memory = 64 * 1024
+	}
+	if params.Iterations == 0 {
+		params.Iterations = 3
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = 2
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = 16
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = 32
+	}
+	return params
+}
+
+// HashPassword will hash the password with random salt
+func (a *Argon2idPassword) HashPassword(password string) string {
+	params := a.params()
+	salt, err := generateSalt(params.SaltLength)
+	if err != nil {
+		return ""
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idID,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		encodeB64(salt),
+		encodeB64(key),
+	)
+}
+
+// ValidatePassword will compare the password with the stored Argon2id hash
+func (a *Argon2idPassword) ValidatePassword(storedPassword, password string) bool {
+	phc, memory, iterations, parallelism, ok := parseArgon2id(storedPassword)
+	if !ok {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), phc.salt, iterations, memory, parallelism, uint32(len(phc.hash)))
+	return subtle.ConstantTimeCompare(key, phc.hash) == 1
+}
+
+// NeedsRehash will check the stored hash isn't Argon2id hash or has different parameters
+func (a *Argon2idPassword) NeedsRehash(storedPassword string) bool {
+	phc, memory, iterations, parallelism, ok := parseArgon2id(storedPassword)
+	if !ok {
+		return true
+	}
+	params := a.params()
+	return memory != params.Memory ||
+		iterations != params.Iterations ||
+		parallelism != params.Parallelism ||
+		uint32(len(phc.salt)) != params.SaltLength ||
+		uint32(len(phc.hash)) != params.KeyLength
+}
+
+// parseArgon2id will parse the Argon2id hash and its parameters
+func parseArgon2id(storedPassword string) (*phcHash, uint32, uint32, uint8, bool) {
+	phc, ok := parsePHC(storedPassword)
+	if !ok || phc.id != argon2idID || phc.version != strconv.Itoa(argon2.Version) {
+		return nil, 0, 0, 0, false
+	}
+	memory, okMemory := phc.uintParam("m", 32)
+	iterations, okIterations := phc.uintParam("t", 32)
+	parallelism, okParallelism := phc.uintParam("p", 8)
+	if !okMemory || !okIterations || !okParallelism || iterations == 0 || parallelism == 0 {
+		return nil, 0, 0, 0, false
+	}
+	return phc, uint32(memory), uint32(iterations), uint8(parallelism), true
+}
@@ -0,0 +1,110 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hashPrefixLength is the length of SHA-1 prefix that used by k-anonymity range query
+const hashPrefixLength = 5
+
+// BreachedChecker will check the password has appeared in the data breach or not
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// BreachedList is BreachedChecker that loads the SHA-1 hashes of breached passwords in memory.
+// the list uses the same format as the Pwned Passwords downloadable file, one `HASH:COUNT` per line,
+// the count is optional. the password is breached if the count >= MinCount
+type BreachedList struct {
+	MinCount int
+
+	hashes map[string]int
+}
+
+// LoadBreachedList will load the breached passwords from the file
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{hashes: make(map[string]int)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, count, ok := parseHashLine(scanner.Text())
+		if ok && len(hash) == sha1.Size*2 {
+			list.hashes[hash] = count
+		}
+	}
+	return list, scanner.Err()
+}
+
+// IsBreached will check the password is in the breached list or not
+func (b *BreachedList) IsBreached(password string) (bool, error) {
+	count, ok := b.hashes[sha1Hex(password)]
+	return ok && count >= b.MinCount, nil
+}
+
+// BreachedRangeDir is BreachedChecker that reads the k-anonymity range files from the directory.
+// every file is named by the first 5 characters of SHA-1 hash, e.g. `5BAA6.txt`, and contains the hash suffix with `SUFFIX:COUNT` per line,
+// the same format as the response of Pwned Passwords range API. It's suitable for the large list that can't be loaded in memory
+type BreachedRangeDir struct {
+	Dir      string
+	MinCount int
+}
+
+// IsBreached will check the hash suffix of password in the range file of its prefix
+// It'll return false if the range file doesn't exist
+func (b *BreachedRangeDir) IsBreached(password string) (bool, error) {
+	hash := sha1Hex(password)
+	file, err := os.Open(filepath.Join(b.Dir, hash[:hashPrefixLength]+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	suffix := hash[hashPrefixLength:]
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, ok := parseHashLine(scanner.Text())
+		if ok && lineSuffix == suffix {
+			return count >= b.MinCount, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// parseHashLine will parse `HASH:COUNT` line, the count is 1 if it's not provided
+func parseHashLine(line string) (string, int, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", 0, false
+	}
+
+	parts := strings.SplitN(line, ":", 2)
+	count := 1
+	if len(parts) == 2 {
+		var err error
+		count, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return "", 0, false
+		}
+	}
+	return strings.ToUpper(parts[0]), count, true
+}
+
+// sha1Hex will return upper case SHA-1 hash of password, the same format as Pwned Passwords
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -2,8 +2,10 @@ package password
 
 import "golang.org/x/crypto/bcrypt"
 
-func hash(str string) string {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(str), 10)
+const defaultBcryptCost = 10
+
+func hash(str string, cost int) string {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(str), cost)
 	return string(hashedPassword)
 }
 
@@ -1,16 +1,92 @@
 package password
 
+import "golang.org/x/crypto/bcrypt"
+
 type PasswordGenerator interface {
 	HashPassword(password string) string
 	ValidatePassword(storedPassword, password string) bool
 }
 
+// Rehasher is optional interface of PasswordGenerator to upgrade the stored hash
+// NeedsRehash should return true if the stored hash isn't created by the generator with the current parameters
+type Rehasher interface {
+	NeedsRehash(storedPassword string) bool
+}
+
 type DefaultBcryptPassword struct{}
 
 func (d *DefaultBcryptPassword) HashPassword(password string) string {
-	return hash(password)
+	return hash(password, defaultBcryptCost)
 }
 
 func (d *DefaultBcryptPassword) ValidatePassword(storedPassword, password string) bool {
 	return compareHash(storedPassword, password)
 }
+
+// BcryptPassword is PasswordGenerator that hash the password with bcrypt and configurable cost, default cost is 10
+type BcryptPassword struct {
+	Cost int
+}
+
+// cost will return the bcrypt cost with default value
+func (b *BcryptPassword) cost() int {
+	if b.Cost < bcrypt.MinCost {
+		return defaultBcryptCost
+	}
+	return b.Cost
+}
+
+func (b *BcryptPassword) HashPassword(password string) string {
+	return hash(password, b.cost())
+}
+
+func (b *BcryptPassword) ValidatePassword(storedPassword, password string) bool {
+	return compareHash(storedPassword, password)
+}
+
+// NeedsRehash will check the stored hash isn't bcrypt hash or has different cost
+func (b *BcryptPassword) NeedsRehash(storedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(storedPassword))
+	return err != nil || cost != b.cost()
+}
+
+// MultiPassword is PasswordGenerator that hash the new password with Primary,
+// and validate the stored password with Primary or one of the Legacy generators.
+// It can be used to migrate the stored hash to the new algorithm, e.g. from bcrypt to Argon2id
+type MultiPassword struct {
+	Primary PasswordGenerator
+	Legacy  []PasswordGenerator
+}
+
+// NewMultiPassword acts as constructor with primary and legacy generators
+func NewMultiPassword(primary PasswordGenerator, legacy ...PasswordGenerator) *MultiPassword {
+	return &MultiPassword{
+		Primary: primary,
+		Legacy:  legacy,
+	}
+}
+
+func (m *MultiPassword) HashPassword(password string) string {
+	return m.Primary.HashPassword(password)
+}
+
+func (m *MultiPassword) ValidatePassword(storedPassword, password string) bool {
+	if m.Primary.ValidatePassword(storedPassword, password) {
+		return true
+	}
+	for _, generator := range m.Legacy {
+		if generator.ValidatePassword(storedPassword, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRehash will check the stored hash needs to be rehashed by Primary
+// the stored hash is never rehashed if Primary doesn't implement Rehasher
+func (m *MultiPassword) NeedsRehash(storedPassword string) bool {
+	if rehasher, ok := m.Primary.(Rehasher); ok {
+		return rehasher.NeedsRehash(storedPassword)
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// phcHash represents the password hash in PHC string format
+// e.g. `$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>`
+type phcHash struct {
+	id      string
+	version string
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+// parsePHC will parse the password hash in PHC string format
+func parsePHC(encoded string) (*phcHash, bool) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return nil, false
+	}
+
+	result := &phcHash{id: parts[1], params: make(map[string]string)}
+	parts = parts[2:]
+	if strings.HasPrefix(parts[0], "v=") {
+		result.version = strings.TrimPrefix(parts[0], "v=")
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	for _, param := range strings.Split(parts[0], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, false
+		}
+		result.params[kv[0]] = kv[1]
+	}
+
+	var err error
+	result.salt, err = base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	result.hash, err = base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(result.hash) == 0 {
+		return nil, false
+	}
+	return result, true
+}
+
+// uintParam will return the numeric param of PHC string
+func (p *phcHash) uintParam(name string, bitSize int) (uint64, bool) {
+	value, err := strconv.ParseUint(p.params[name], 10, bitSize)
+	return value, err == nil
+}
+
+// encodeB64 will encode salt or hash with standard base64 without padding as described in PHC string format
+func encodeB64(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+// generateSalt will generate random salt
+func generateSalt(length uint32) ([]byte, error) {
+	salt := make([]byte, length)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,110 @@
+package password
+
+import (
+	"errors"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrPasswordTooShort  = errors.New("password is too short")
+	ErrPasswordTooLong   = errors.New("password is too long")
+	ErrPasswordUpper     = errors.New("password must contain upper case letter")
+	ErrPasswordLower     = errors.New("password must contain lower case letter")
+	ErrPasswordDigit     = errors.New("password must contain digit")
+	ErrPasswordSymbol    = errors.New("password must contain special character")
+	ErrPasswordTooWeak   = errors.New("password is too easy to guess")
+	ErrPasswordBreached  = errors.New("password has appeared in a data breach")
+	ErrPasswordReused    = errors.New("password has been used recently")
+	ErrPasswordCharClass = errors.New("password doesn't contain enough character classes")
+)
+
+// Policy contains the password rules that checked when the password is set by Register, ResetPassword or ChangePassword.
+// the zero value of each rule will disable the rule
+type Policy struct {
+	// MinLength and MaxLength are counted in characters
+	MinLength int
+	MaxLength int
+
+	// RequireUpper, RequireLower, RequireDigit and RequireSymbol will require the character class
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// MinCharClasses is the minimum number of character classes (upper, lower, digit, symbol), e.g. 3 of 4
+	MinCharClasses int
+
+	// MinStrength is the minimum strength score from 0 to 4 that estimated by Strength
+	MinStrength int
+
+	// Breached is used to reject the password that has appeared in the data breach
+	Breached BreachedChecker
+
+	// HistorySize is the number of previous passwords that can't be reused
+	HistorySize int
+
+	// MaxAge is the maximum age of password, user must change the password on login after the password is expired
+	MaxAge time.Duration
+}
+
+// Validate will check the password with the policy rules, userInputs (e.g. username and email) are used to estimate the strength.
+// HistorySize and MaxAge aren't checked by Validate, they're checked by the auth module
+func (p *Policy) Validate(password string, userInputs ...string) error {
+	length := len([]rune(password))
+	if p.MinLength > 0 && length < p.MinLength {
+		return ErrPasswordTooShort
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return ErrPasswordTooLong
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return ErrPasswordUpper
+	case p.RequireLower && !lower:
+		return ErrPasswordLower
+	case p.RequireDigit && !digit:
+		return ErrPasswordDigit
+	case p.RequireSymbol && !symbol:
+		return ErrPasswordSymbol
+	}
+
+	classes := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.MinCharClasses {
+		return ErrPasswordCharClass
+	}
+
+	if p.MinStrength > 0 && Strength(password, userInputs...) < p.MinStrength {
+		return ErrPasswordTooWeak
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return ErrPasswordBreached
+		}
+	}
+	return nil
+}
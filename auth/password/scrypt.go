@@ -0,0 +1,114 @@
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const scryptID = "scrypt"
+
+// ScryptPassword is PasswordGenerator that hash the password with scrypt,
+// the hash is encoded in PHC string format, e.g. `$scrypt$ln=15,r=8,p=1$<salt>$<hash>`.
+// the zero value of parameters will use the default value
+type ScryptPassword struct {
+	// LogN is log2 of CPU/memory cost N, default is 15 (N = 32768)
+	LogN uint8
+
+	// BlockSize is the r parameter, default is 8
+	BlockSize int
+
+	// Parallelism is the p parameter, default is 1
+	Parallelism int
+
+	// SaltLength and KeyLength are in bytes, default is 16 and 32
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// params will return the parameters with default value
+func (s *ScryptPassword) params() ScryptPassword {
+	params := *s
+	if params.LogN == 0 {
+		params.LogN = 15
+	}
+	if params.BlockSize == 0 {
+		params.BlockSize = 8
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = 1
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = 16
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = 32
+	}
+	return params
+}
+
+// HashPassword will hash the password with random salt
+func (s *ScryptPassword) HashPassword(password string) string {
+	params := s.params()
+	salt, err := generateSalt(params.SaltLength)
+	if err != nil {
+		return ""
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<params.LogN, params.BlockSize, params.Parallelism, int(params.KeyLength))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(
+		"$%s$ln=%d,r=%d,p=%d$%s$%s",
+		scryptID,
+		params.LogN,
+		params.BlockSize,
+		params.Parallelism,
+		encodeB64(salt),
+		encodeB64(key),
+	)
+}
+
+// ValidatePassword will compare the password with the stored scrypt hash
+func (s *ScryptPassword) ValidatePassword(storedPassword, password string) bool {
+	phc, logN, blockSize, parallelism, ok := parseScrypt(storedPassword)
+	if !ok {
+		return false
+	}
+	key, err := scrypt.Key([]byte(password), phc.salt, 1<<logN, blockSize, parallelism, len(phc.hash))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, phc.hash) == 1
+}
+
+// NeedsRehash will check the stored hash isn't scrypt hash or has different parameters
+func (s *ScryptPassword) NeedsRehash(storedPassword string) bool {
+	phc, logN, blockSize, parallelism, ok := parseScrypt(storedPassword)
+	if !ok {
+		return true
+	}
+	params := s.params()
+	return logN != params.LogN ||
+		blockSize != params.BlockSize ||
+		parallelism != params.Parallelism ||
+		uint32(len(phc.salt)) != params.SaltLength ||
+		uint32(len(phc.hash)) != params.KeyLength
+}
+
+// parseScrypt will parse the scrypt hash and its parameters
+func parseScrypt(storedPassword string) (*phcHash, uint8, int, int, bool) {
+	phc, ok := parsePHC(storedPassword)
+	if !ok || phc.id != scryptID {
+		return nil, 0, 0, 0, false
+	}
+	logN, okLogN := phc.uintParam("ln", 8)
+	blockSize, okBlockSize := phc.uintParam("r", 31)
+	parallelism, okParallelism := phc.uintParam("p", 31)
+	if !okLogN || !okBlockSize || !okParallelism || logN == 0 || logN >= 32 {
+		return nil, 0, 0, 0, false
+	}
+	return phc, uint8(logN), int(blockSize), int(parallelism), true
+}
@@ -0,0 +1,160 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords is the most common passwords, they're guessed first by the attacker
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567", "dragon",
+	"123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow", "master", "666666",
+	"qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212",
+	"000000", "qazwsx", "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "klaster", "112233", "george", "computer",
+	"michelle", "jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777",
+	"pass", "maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321", "dallas",
+	"austin", "thunder", "taylor", "matrix", "welcome", "admin", "login", "passw0rd", "secret", "changeme",
+	"zaq12wsx", "qwe123", "asdf1234", "q1w2e3r4", "1q2w3e4r", "football1", "baseball1", "abcd1234", "password1", "qwerty1",
+}
+
+// keyboardRows is used to detect the adjacent keys pattern, e.g. `qwerty` or `asdf`
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// leetReplacer will replace the common l33t substitutions, e.g. `p@ssw0rd` to `password`
+var leetReplacer = strings.NewReplacer(
+	"4", "a", "@", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t",
+)
+
+// Strength will estimate the strength score of password from 0 (too guessable) to 4 (very unguessable)
+// like zxcvbn, it estimates the number of guesses by the common passwords, user inputs (e.g. username and email),
+// l33t substitutions, repeated characters, sequences, and keyboard patterns
+func Strength(password string, userInputs ...string) int {
+	guesses := estimateGuesses(password, userInputs)
+	switch {
+	case guesses < 1e3:
+		return 0
+	case guesses < 1e6:
+		return 1
+	case guesses < 1e8:
+		return 2
+	case guesses < 1e10:
+		return 3
+	}
+	return 4
+}
+
+// estimateGuesses will estimate the number of guesses to crack the password
+func estimateGuesses(password string, userInputs []string) float64 {
+	if password == "" {
+		return 0
+	}
+
+	lower := strings.ToLower(password)
+	words := dictionary(userInputs)
+
+	// the dictionary word with digits or symbols at the beginning or the end, e.g. `password123!`
+	core := leetReplacer.Replace(strings.TrimFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+	for rank, word := range words {
+		if lower == word || core == word {
+			affixes := float64(len([]rune(lower)) - len([]rune(word)))
+			return float64(rank+1) * math.Pow(10, math.Max(affixes, 0))
+		}
+	}
+
+	// remove user inputs that appear in the password, they're cheap to guess
+	bits := 0.0
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		if len(input) >= 3 && strings.Contains(lower, input) {
+			lower = strings.Replace(lower, input, "", -1)
+			bits += math.Log2(float64(len(userInputs)) + 1)
+		}
+	}
+
+	runes := []rune(lower)
+	charset := math.Log2(float64(charsetSize(password)))
+	for i, r := range runes {
+		if i > 0 && isPattern(runes[i-1], r) {
+			bits++
+			continue
+		}
+		bits += charset
+	}
+	return math.Pow(2, bits)
+}
+
+// dictionary will return the common passwords and user inputs as lower case words
+func dictionary(userInputs []string) []string {
+	words := make([]string, 0, len(commonPasswords)+len(userInputs))
+	words = append(words, commonPasswords...)
+	for _, input := range userInputs {
+		if input != "" {
+			words = append(words, strings.ToLower(input))
+		}
+	}
+	return words
+}
+
+// isPattern will check the character is repeated, sequence, or adjacent key of the previous character
+func isPattern(prev, current rune) bool {
+	diff := current - prev
+	if diff >= -1 && diff <= 1 {
+		return true
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		j := strings.IndexRune(row, current)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// charsetSize will return the size of character set that used by password
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return size
+}
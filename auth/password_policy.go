@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/password"
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/event"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrInvalidPasswordChange = errors.New("invalid or expired password change token")
+)
+
+const (
+	passwordChangePrefix = "guardian:password_change:"
+	passwordChangeTTL    = 10 * time.Minute
+)
+
+// passwordChange represents the pending change of expired password that stored in the session store
+type passwordChange struct {
+	UserID    int64     `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// ErrPasswordExpired is returned by Authenticate when the password is valid but older than the maximum password age
+// the password must be changed by ChangeExpiredPassword with ChangeToken before user can login
+type ErrPasswordExpired struct {
+	ChangeToken string
+	ExpiredAt   time.Time
+}
+
+func (e *ErrPasswordExpired) Error() string {
+	return "password is expired and must be changed"
+}
+
+// checkPasswordAge will create the password change token if the password of user is expired
+// *ErrPasswordExpired will be returned with the change token
+func (a *Auth) checkPasswordAge(user *schema.User) error {
+	if a.passwordPolicy == nil || a.passwordPolicy.MaxAge <= 0 || user.PasswordAge() <= a.passwordPolicy.MaxAge {
+		return nil
+	}
+
+	changeToken, err := randomToken()
+	if err != nil {
+		return err
+	}
+	expiredAt := time.Now().Add(passwordChangeTTL)
+	err = a.putJSON(passwordChangePrefix+hashToken(changeToken), passwordChange{
+		UserID:    user.ID,
+		ExpiredAt: expiredAt,
+	}, passwordChangeTTL)
+	if err != nil {
+		return err
+	}
+	return &ErrPasswordExpired{
+		ChangeToken: changeToken,
+		ExpiredAt:   expiredAt,
+	}
+}
+
+// validatePassword will validate the new password of user with the password validator and password policy
+// the password that is the same as the current password or one of the previous passwords will be rejected
+func (a *Auth) validatePassword(user *schema.User, newPassword string) error {
+	err := a.dbSchema.User(user).ValidatePassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if user.Password != "" && a.passwordStrategy.ValidatePassword(user.Password, newPassword) {
+		return password.ErrPasswordReused
+	}
+	if a.passwordPolicy == nil {
+		return nil
+	}
+
+	err = a.passwordPolicy.Validate(newPassword, user.Username, user.Email)
+	if err != nil {
+		return err
+	}
+
+	if a.passwordPolicy.HistorySize > 0 && user.ID > 0 {
+		previous, err := a.dbSchema.PasswordHistory(nil).GetRecentPasswords(user.ID, a.passwordPolicy.HistorySize)
+		if err != nil {
+			return err
+		}
+		for _, hash := range previous {
+			if a.passwordStrategy.ValidatePassword(hash, newPassword) {
+				return password.ErrPasswordReused
+			}
+		}
+	}
+	return nil
+}
+
+// recordPassword will store the password hash in the password history and keep the last HistorySize hashes
+func (a *Auth) recordPassword(user *schema.User) error {
+	if a.passwordPolicy == nil || a.passwordPolicy.HistorySize <= 0 {
+		return nil
+	}
+
+	history := a.dbSchema.PasswordHistory(&schema.PasswordHistory{
+		UserID:   user.ID,
+		Password: user.Password,
+	})
+	err := history.CreatePasswordHistory()
+	if err != nil {
+		return err
+	}
+	return history.PrunePasswordHistory(user.ID, a.passwordPolicy.HistorySize)
+}
+
+// updatePassword will hash the new password, update the password of user and record it in the password history
+// the password change is published as event.PasswordChanged.
+// the new password must be validated by validatePassword before
+func (a *Auth) updatePassword(user *schema.User, newPassword string) error {
+	user = a.dbSchema.User(user)
+	err := user.UpdatePassword(a.passwordStrategy.HashPassword(newPassword))
+	if err != nil {
+		return err
+	}
+	err = a.recordPassword(user)
+	if err != nil {
+		return err
+	}
+
+	a.publish(event.PasswordChanged{
+		UserID:     user.ID,
+		OccurredAt: time.Now(),
+	})
+	return nil
+}
+
+// ChangePassword will change the password of the logged user, the current password must be valid.
+// the other sessions of user will be revoked, the current session is kept.
+// the password can't be changed by the impersonated session.
+// You should using middleware authentication before call this function
+func (a *Auth) ChangePassword(r *http.Request, currentPassword, newPassword string) error {
+	user := GetUserLogin(r)
+	if user == nil {
+		return ErrInvalidUserLogin
+	}
+	if GetImpersonator(r) != nil {
+		return ErrImpersonationForbidden
+	}
+	if !a.passwordStrategy.ValidatePassword(user.Password, currentPassword) {
+		return ErrInvalidPasswordLogin
+	}
+
+	err := a.validatePassword(user, newPassword)
+	if err != nil {
+		return err
+	}
+	err = a.updatePassword(user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	currentSession := GetSessionID(r)
+	if currentSession == "" {
+		return nil
+	}
+	return a.RevokeAllSessions(user.ID, currentSession)
+}
+
+// restorePasswordChange will put back the change token that consumed by ChangeExpiredPassword, so user can retry with the same token
+func (a *Auth) restorePasswordChange(key string, change *passwordChange) error {
+	ttl := time.Until(change.ExpiredAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return a.putJSON(key, change, ttl)
+}
+
+// ChangeExpiredPassword will change the expired password with the change token of *ErrPasswordExpired
+// the token can only be used once, user must login again with the new password.
+// the token can be used again if the new password is rejected, e.g. by the password policy
+func (a *Auth) ChangeExpiredPassword(changeToken, newPassword string) (*schema.User, error) {
+	// the token is consumed atomically, so the concurrent requests can't use the same token
+	key := passwordChangePrefix + hashToken(changeToken)
+	data, err := a.sessionStore.Take(key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, ErrInvalidPasswordChange
+		}
+		return nil, err
+	}
+	var change passwordChange
+	err = json.Unmarshal([]byte(data), &change)
+	if err != nil {
+		return nil, err
+	}
+	if !change.ExpiredAt.After(time.Now()) {
+		return nil, ErrInvalidPasswordChange
+	}
+
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": change.UserID,
+	})
+	if err != nil {
+		a.restorePasswordChange(key, &change)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidPasswordChange
+	}
+
+	err = a.validatePassword(user, newPassword)
+	if err == nil {
+		err = a.updatePassword(user, newPassword)
+	}
+	if err != nil {
+		a.restorePasswordChange(key, &change)
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+// passwordResetTTL will return the lifetime of password reset token
+func (a *Auth) passwordResetTTL() time.Duration {
+	if a.passwordResetInSeconds <= 0 {
+		return defaultPasswordResetTTL
+	}
+	return time.Duration(a.passwordResetInSeconds) * time.Second
+}
+
+// RequestPasswordReset will create the password reset token and deliver it to user by Notifier
+// the identifier is email or username depend on the login method.
+// It'll return nil if user doesn't exist, so the caller can't guess the registered user.
+// the previous password reset token of user can't be used anymore
+func (a *Auth) RequestPasswordReset(identifier string) error {
+	if a.notifier == nil {
+		return ErrNoNotifier
+	}
+
+	user, err := a.findUserByIdentifier(identifier)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.Active {
+		return nil
+	}
+	return a.issueUserToken(NotificationPasswordReset, user, a.passwordResetTTL())
+}
+
+// ResetPassword will change the password of user with the password reset token
+// the new password is validated by the password validator and password policy, and hashed by the password strategy.
+// the token can only be used once, and all sessions of user will be revoked.
+// the token can be used again if the new password is rejected, e.g. by the password policy
+func (a *Auth) ResetPassword(token, newPassword string) error {
+	record, err := a.takeUserToken(NotificationPasswordReset, token)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": record.UserID,
+	})
+	if err != nil {
+		a.restoreUserToken(NotificationPasswordReset, token, record)
+		return err
+	}
+	if user == nil {
+		return ErrInvalidResetToken
+	}
+
+	err = a.validatePassword(user, newPassword)
+	if err != nil {
+		a.restoreUserToken(NotificationPasswordReset, token, record)
+		return err
+	}
+
+	err = a.updatePassword(user, newPassword)
+	if err != nil {
+		a.restoreUserToken(NotificationPasswordReset, token, record)
+		return err
+	}
+
+	err = a.clearUserTokenIndex(NotificationPasswordReset, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = a.UnlockUser(user)
+	if err != nil {
+		return err
+	}
+	return a.RevokeAllSessions(user.ID, "")
+}
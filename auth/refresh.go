@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+var (
+	ErrRefreshDisabled      = errors.New("refresh token is disabled")
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token has been reused, all tokens in the family are revoked")
+	ErrCreatingRefreshToken = errors.New("error while create a new refresh token")
+)
+
+const (
+	refreshTokenPrefix  = "guardian:refresh:"
+	refreshFamilyPrefix = "guardian:refresh_family:"
+)
+
+// TokenPair contains access token and refresh token that issued by SignInWithRefresh and Refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// refreshRecord represents a refresh token that stored in the session store
+// the rotated refresh token is kept until expired, it's detected as reused because the family has the next refresh token
+type refreshRecord struct {
+	FamilyID  string    `json:"family_id"`
+	UserID    int64     `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// refreshFamily represents a chain of rotated refresh tokens that created by one sign in
+// it keeps the current access token and refresh token, so the whole family can be revoked
+// the session metadata is kept to create the session of the next access token
+type refreshFamily struct {
+	UserID       int64  `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IPAddress    string `json:"ip_address"`
+	UserAgent    string `json:"user_agent"`
+}
+
+// refreshTTL will return the refresh token lifetime as time.Duration
+func (a *Auth) refreshTTL() time.Duration {
+	return time.Duration(a.refreshInSeconds) * time.Second
+}
+
+// newFamilyID will generate a new refresh token family id
+func newFamilyID() string {
+	return uuid.NewV4().String()
+}
+
+// putJSON is helper func to store the value as json in the session store
+func (a *Auth) putJSON(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return a.sessionStore.Put(key, string(data), ttl)
+}
+
+// getJSON is helper func to get the json value from the session store
+func (a *Auth) getJSON(key string, value interface{}) error {
+	data, err := a.sessionStore.Get(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), value)
+}
+
+// SignInWithRefresh will authenticate user login and return access token and refresh token
+// The access token is used as the token returned by SignIn, and the refresh token is used to get a new token pair by Refresh
+func (a *Auth) SignInWithRefresh(params LoginParams) (*schema.User, *TokenPair, error) {
+	if a.refreshInSeconds <= 0 {
+		return nil, nil, ErrRefreshDisabled
+	}
+
+	loggedUser, err := a.Authenticate(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	family := &refreshFamily{
+		IPAddress: params.IPAddress,
+		UserAgent: params.UserAgent,
+	}
+	pair, err := a.issueTokenPair(newFamilyID(), family, loggedUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loggedUser, pair, nil
+}
+
+// issueTokenPair will create a new access token session and refresh token within the family
+// the family that already has the access token is rotated by Refresh, so it isn't published as a new login
+func (a *Auth) issueTokenPair(familyID string, family *refreshFamily, user *schema.User) (*TokenPair, error) {
+	accessToken, err := a.newSession(user, sessionParams{
+		ipAddress: family.IPAddress,
+		userAgent: family.UserAgent,
+		familyID:  familyID,
+		refreshed: family.AccessToken != "",
+	})
+	if err != nil {
+		return nil, ErrCreatingToken
+	}
+
+	refreshToken := a.tokenStrategy.GenerateToken()
+	err = a.putJSON(refreshTokenPrefix+refreshToken, refreshRecord{
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiredAt: time.Now().Add(a.refreshTTL()),
+	}, a.refreshTTL())
+	if err != nil {
+		return nil, ErrCreatingRefreshToken
+	}
+
+	family.UserID = user.ID
+	family.AccessToken = accessToken
+	family.RefreshToken = refreshToken
+	err = a.putJSON(refreshFamilyPrefix+familyID, family, a.refreshTTL())
+	if err != nil {
+		return nil, ErrCreatingRefreshToken
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    a.expiredInSeconds,
+	}, nil
+}
+
+// Refresh will rotate the refresh token and return a new token pair
+// The old refresh token and access token can't be used anymore, except jwt access token without the denylist that valid until expired.
+// If the old refresh token is replayed, it's considered as stolen token,
+// then the whole token family will be revoked and ErrRefreshTokenReused will be returned.
+// the refresh token can be used again if the rotation fails, e.g. by the database error
+func (a *Auth) Refresh(refreshToken string) (*TokenPair, error) {
+	if a.refreshInSeconds <= 0 {
+		return nil, ErrRefreshDisabled
+	}
+
+	// the refresh token is consumed atomically, so the concurrent requests with the same token can't rotate it twice,
+	// it's put back after the rotation, or restored if the rotation fails
+	data, err := a.sessionStore.Take(refreshTokenPrefix + refreshToken)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	var record refreshRecord
+	err = json.Unmarshal([]byte(data), &record)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Until(record.ExpiredAt)
+	if ttl <= 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var family refreshFamily
+	err = a.getJSON(refreshFamilyPrefix+record.FamilyID, &family)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil, ErrInvalidRefreshToken
+		}
+		a.putJSON(refreshTokenPrefix+refreshToken, record, ttl)
+		return nil, err
+	}
+
+	// reuse detection, the family only has the next refresh token after the rotation is completed
+	if family.RefreshToken != refreshToken {
+		err = a.revokeRefreshFamily(record.FamilyID, &family)
+		if err != nil && err != ErrTokenNotRevocable {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	pair, err := a.rotateRefreshFamily(&record, &family)
+	if err != nil {
+		// the rotation isn't completed, so the refresh token is restored and the client can retry with it
+		a.putJSON(refreshTokenPrefix+refreshToken, record, ttl)
+		return nil, err
+	}
+
+	// the rotated refresh token is kept until expired for reuse detection,
+	// the new token pair is already issued, so the failure only skips the reuse detection of this token
+	a.putJSON(refreshTokenPrefix+refreshToken, record, ttl)
+	return pair, nil
+}
+
+// rotateRefreshFamily will replace the access token of family and issue the next token pair
+func (a *Auth) rotateRefreshFamily(record *refreshRecord, family *refreshFamily) (*TokenPair, error) {
+	user, err := a.dbSchema.User(nil).FindUser(map[string]interface{}{
+		"id": record.UserID,
+	})
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.Active {
+		return nil, ErrUserNotActive
+	}
+
+	// the old access token is replaced by the new one, the family is kept
+	// stateless jwt token without the denylist can't be revoked, it's valid until expired
+	err = a.revokeSession(family.AccessToken, false)
+	if err != nil && err != ErrTokenNotRevocable {
+		return nil, err
+	}
+
+	return a.issueTokenPair(record.FamilyID, family, user)
+}
+
+// RevokeRefreshToken will revoke the whole token family of the refresh token
+// It can be used to logout the client that using refresh token.
+// ErrTokenNotRevocable will be returned if the access token is jwt token and the denylist is disabled, the refresh token is still revoked
+func (a *Auth) RevokeRefreshToken(refreshToken string) error {
+	var record refreshRecord
+	err := a.getJSON(refreshTokenPrefix+refreshToken, &record)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	var family refreshFamily
+	err = a.getJSON(refreshFamilyPrefix+record.FamilyID, &family)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil
+		}
+		return err
+	}
+	return a.revokeRefreshFamily(record.FamilyID, &family)
+}
+
+// revokeRefreshFamily will delete the current refresh token, the family itself, and the current access token
+// the refresh token is deleted first, so it's revoked even though the access token can't be revoked
+func (a *Auth) revokeRefreshFamily(familyID string, family *refreshFamily) error {
+	err := a.sessionStore.Delete(refreshTokenPrefix + family.RefreshToken)
+	if err != nil {
+		return err
+	}
+	err = a.sessionStore.Delete(refreshFamilyPrefix + familyID)
+	if err != nil {
+		return err
+	}
+	return a.revokeSession(family.AccessToken, false)
+}
+
+// deleteRefreshFamily will delete the current refresh token and the family by family id
+// it's used when the session that issued by the family is revoked
+func (a *Auth) deleteRefreshFamily(familyID string) error {
+	var family refreshFamily
+	err := a.getJSON(refreshFamilyPrefix+familyID, &family)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return nil
+		}
+		return err
+	}
+	err = a.sessionStore.Delete(refreshTokenPrefix + family.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return a.sessionStore.Delete(refreshFamilyPrefix + familyID)
+}
@@ -0,0 +1,234 @@
+package session
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value     string
+	expiredAt time.Time
+}
+
+// isExpired will check the item is already expired or not
+func (m memoryItem) isExpired(now time.Time) bool {
+	return !m.expiredAt.IsZero() && !now.Before(m.expiredAt)
+}
+
+type memorySet struct {
+	members   map[string]struct{}
+	expiredAt time.Time
+}
+
+// isExpired will check the set is already expired or not
+func (m *memorySet) isExpired(now time.Time) bool {
+	return !m.expiredAt.IsZero() && !now.Before(m.expiredAt)
+}
+
+// MemoryStore is SessionStore implementation that store the session in the process memory
+// The expired session will be swept periodically by the background goroutine
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+	sets  map[string]*memorySet
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewMemoryStore acts as constructor, sweepInterval is the interval of expired session sweeping
+// if sweepInterval <= 0, expired session only be removed when it's accessed
+func NewMemoryStore(sweepInterval time.Duration) *MemoryStore {
+	store := &MemoryStore{
+		items: make(map[string]memoryItem),
+		sets:  make(map[string]*memorySet),
+		stop:  make(chan struct{}),
+	}
+	if sweepInterval > 0 {
+		go store.sweeper(sweepInterval)
+	}
+	return store
+}
+
+// sweeper will run Sweep in every interval until the store is closed
+func (s *MemoryStore) sweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Sweep will remove all expired session from the memory
+func (s *MemoryStore) Sweep() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, item := range s.items {
+		if item.isExpired(now) {
+			delete(s.items, key)
+		}
+	}
+	for key, set := range s.sets {
+		if set.isExpired(now) {
+			delete(s.sets, key)
+		}
+	}
+}
+
+// Close will stop the background sweeper
+func (s *MemoryStore) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Put will set the value with specific key and ttl
+func (s *MemoryStore) Put(key string, value string, ttl time.Duration) error {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiredAt = time.Now().Add(ttl)
+	}
+	s.mu.Lock()
+	s.items[key] = item
+	s.mu.Unlock()
+	return nil
+}
+
+// Get will return the value by specific key
+// ErrSessionNotFound will be returned if the key is not exist or already expired
+func (s *MemoryStore) Get(key string) (string, error) {
+	s.mu.RLock()
+	item, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok || item.isExpired(time.Now()) {
+		return "", ErrSessionNotFound
+	}
+	return item.value, nil
+}
+
+// Take will return the value by specific key and delete it atomically
+// ErrSessionNotFound will be returned if the key is not exist or already expired
+func (s *MemoryStore) Take(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	delete(s.items, key)
+	if item.isExpired(time.Now()) {
+		return "", ErrSessionNotFound
+	}
+	return item.value, nil
+}
+
+// Incr will increment the counter by specific key and return the new value
+// the counter that not exist or already expired is created with value 1 and the ttl
+func (s *MemoryStore) Incr(key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok || item.isExpired(now) {
+		item = memoryItem{value: "0"}
+		if ttl > 0 {
+			item.expiredAt = now.Add(ttl)
+		}
+	}
+	counter, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	counter++
+	item.value = strconv.FormatInt(counter, 10)
+	s.items[key] = item
+	return counter, nil
+}
+
+// Delete will delete the value by specific key
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Touch will renew the ttl of existing key
+func (s *MemoryStore) Touch(key string, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok || item.isExpired(now) {
+		return ErrSessionNotFound
+	}
+	item.expiredAt = time.Time{}
+	if ttl > 0 {
+		item.expiredAt = now.Add(ttl)
+	}
+	s.items[key] = item
+	return nil
+}
+
+// AddMember will add the member to the set with specific key and renew the ttl of set
+func (s *MemoryStore) AddMember(key string, member string, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.sets[key]
+	if !ok || set.isExpired(now) {
+		set = &memorySet{members: make(map[string]struct{})}
+		s.sets[key] = set
+	}
+	set.members[member] = struct{}{}
+	set.expiredAt = time.Time{}
+	if ttl > 0 {
+		set.expiredAt = now.Add(ttl)
+	}
+	return nil
+}
+
+// RemoveMember will remove the member from the set with specific key
+// the set is deleted if there's no member left
+func (s *MemoryStore) RemoveMember(key string, member string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.sets[key]
+	if !ok {
+		return nil
+	}
+	delete(set.members, member)
+	if len(set.members) == 0 {
+		delete(s.sets, key)
+	}
+	return nil
+}
+
+// Members will return all members of the set with specific key
+// empty slice will be returned if the set is not exist or already expired
+func (s *MemoryStore) Members(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.sets[key]
+	if !ok || set.isExpired(time.Now()) {
+		return []string{}, nil
+	}
+	members := make([]string, 0, len(set.members))
+	for member := range set.members {
+		members = append(members, member)
+	}
+	return members, nil
+}
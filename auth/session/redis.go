@@ -0,0 +1,130 @@
+package session
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// takeScript will get and delete the key in one atomic step
+var takeScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value
+`)
+
+// incrScript will increment the counter and set the ttl when the counter is created
+var incrScript = redis.NewScript(`
+local counter = redis.call("INCR", KEYS[1])
+if counter == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return counter
+`)
+
+// RedisStore is SessionStore implementation that store the session in the redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore acts as constructor with the required redis client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Put will set the value with specific key and ttl
+func (s *RedisStore) Put(key string, value string, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.Set(key, value, ttl).Err()
+}
+
+// Get will return the value by specific key
+// ErrSessionNotFound will be returned if the key is not exist or already expired
+func (s *RedisStore) Get(key string) (string, error) {
+	value, err := s.client.Get(key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrSessionNotFound
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// Take will return the value by specific key and delete it atomically
+// ErrSessionNotFound will be returned if the key is not exist or already expired
+func (s *RedisStore) Take(key string) (string, error) {
+	value, err := takeScript.Run(s.client, []string{key}).String()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrSessionNotFound
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// Incr will increment the counter by specific key and return the new value
+// the counter that not exist or already expired is created with value 1 and the ttl
+func (s *RedisStore) Incr(key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(s.client, []string{key}, int64(ttl/time.Millisecond)).Int64()
+}
+
+// Delete will delete the value by specific key
+func (s *RedisStore) Delete(key string) error {
+	return s.client.Del(key).Err()
+}
+
+// Touch will renew the ttl of existing key
+func (s *RedisStore) Touch(key string, ttl time.Duration) error {
+	var ok bool
+	var err error
+	if ttl > 0 {
+		ok, err = s.client.Expire(key, ttl).Result()
+	} else {
+		ok, err = s.client.Persist(key).Result()
+		if err == nil && !ok {
+			// persist return false when the key has no ttl, make sure the key is exist
+			var count int64
+			count, err = s.client.Exists(key).Result()
+			ok = count > 0
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// AddMember will add the member to the set with specific key and renew the ttl of set
+func (s *RedisStore) AddMember(key string, member string, ttl time.Duration) error {
+	_, err := s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.SAdd(key, member)
+		if ttl > 0 {
+			pipe.Expire(key, ttl)
+		} else {
+			pipe.Persist(key)
+		}
+		return nil
+	})
+	return err
+}
+
+// RemoveMember will remove the member from the set with specific key
+// the set is deleted by redis if there's no member left
+func (s *RedisStore) RemoveMember(key string, member string) error {
+	return s.client.SRem(key, member).Err()
+}
+
+// Members will return all members of the set with specific key
+// empty slice will be returned if the set is not exist or already expired
+func (s *RedisStore) Members(key string) ([]string, error) {
+	return s.client.SMembers(key).Result()
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session is not exist")
+)
+
+// SessionStore represents the storage behaviour that used by guardian to keep the login session
+// Every value is stored with the ttl, ttl <= 0 means the value will never expire
+// Take must get and delete the value atomically, so only one caller can take the same value.
+// Incr must increment the counter atomically, the ttl is only applied when the counter is created
+//
+// AddMember, RemoveMember and Members manage the set of values by key, every change of the set must be atomic,
+// so the concurrent changes aren't lost. The key of set must not be used by Put, Get, Take, Delete, and Touch
+type SessionStore interface {
+	Put(key string, value string, ttl time.Duration) error
+	Get(key string) (string, error)
+	Take(key string) (string, error)
+	Incr(key string, ttl time.Duration) (int64, error)
+	Delete(key string) error
+	Touch(key string, ttl time.Duration) error
+
+	AddMember(key string, member string, ttl time.Duration) error
+	RemoveMember(key string, member string) error
+	Members(key string) ([]string, error)
+}
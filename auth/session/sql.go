@@ -0,0 +1,211 @@
+package session
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+// SQLStore is SessionStore implementation that store the session in the `guard_session` table
+type SQLStore struct {
+	dbContract schema.DbContract
+}
+
+// NewSQLStore acts as constructor, it'll reuse the database connection of guardian schema
+func NewSQLStore(guardSchema *schema.Schema) *SQLStore {
+	return &SQLStore{dbContract: guardSchema.DbConnection}
+}
+
+// expiredAt is helper func to convert ttl to nullable expired time
+func expiredAt(ttl time.Duration) interface{} {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().Add(ttl)
+}
+
+const putSessionQuery = `
+	INSERT INTO guard_session (
+		session_key,
+		value,
+		expired_at
+	) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE value = ?, expired_at = ?
+`
+
+// Put will set the value with specific key and ttl
+func (s *SQLStore) Put(key string, value string, ttl time.Duration) error {
+	expired := expiredAt(ttl)
+	_, err := s.dbContract.Exec(
+		putSessionQuery,
+		key,
+		value,
+		expired,
+		value,
+		expired,
+	)
+	return err
+}
+
+const getSessionQuery = `
+	SELECT value FROM guard_session 
+	WHERE session_key = ? AND (expired_at IS NULL OR expired_at > ?) 
+	LIMIT 1
+`
+
+// Get will return the value by specific key
+// ErrSessionNotFound will be returned if the key is not exist or already expired
+func (s *SQLStore) Get(key string) (string, error) {
+	var value string
+	result := s.dbContract.QueryRow(getSessionQuery, key, time.Now())
+	err := result.Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrSessionNotFound
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+const takeSessionQuery = `DELETE FROM guard_session WHERE session_key = ? AND value = ?`
+
+// Take will return the value by specific key and delete it atomically
+// the value is only returned to the caller that deleted the row, the other concurrent callers get ErrSessionNotFound
+func (s *SQLStore) Take(key string) (string, error) {
+	value, err := s.Get(key)
+	if err != nil {
+		return "", err
+	}
+	result, err := s.dbContract.Exec(takeSessionQuery, key, value)
+	if err != nil {
+		return "", err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if affected == 0 {
+		return "", ErrSessionNotFound
+	}
+	return value, nil
+}
+
+const incrSessionQuery = `
+	INSERT INTO guard_session (
+		session_key,
+		value,
+		expired_at
+	) VALUES (?, '1', ?) ON DUPLICATE KEY UPDATE 
+		value = LAST_INSERT_ID(IF(expired_at IS NOT NULL AND expired_at <= ?, 1, CAST(value AS UNSIGNED) + 1)),
+		expired_at = IF(expired_at IS NOT NULL AND expired_at <= ?, ?, expired_at)
+`
+
+// Incr will increment the counter by specific key and return the new value
+// the counter that not exist or already expired is created with value 1 and the ttl.
+// the new value of existing counter is returned by LAST_INSERT_ID, so it's done in one statement
+func (s *SQLStore) Incr(key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	expired := expiredAt(ttl)
+	result, err := s.dbContract.Exec(incrSessionQuery, key, expired, now, now, expired)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 1 {
+		// the counter is created
+		return 1, nil
+	}
+	return result.LastInsertId()
+}
+
+const deleteSessionQuery = `DELETE FROM guard_session WHERE session_key = ?`
+
+// Delete will delete the value by specific key
+func (s *SQLStore) Delete(key string) error {
+	_, err := s.dbContract.Exec(deleteSessionQuery, key)
+	return err
+}
+
+const touchSessionQuery = `
+	UPDATE guard_session SET expired_at = ? 
+	WHERE session_key = ? AND (expired_at IS NULL OR expired_at > ?)
+`
+
+// Touch will renew the ttl of existing key
+func (s *SQLStore) Touch(key string, ttl time.Duration) error {
+	result, err := s.dbContract.Exec(touchSessionQuery, expiredAt(ttl), key, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// mysql doesn't count the unchanged row, make sure the key is really not exist
+		_, err = s.Get(key)
+		return err
+	}
+	return nil
+}
+
+// memberKey is helper func to get the session key of set member, every member is stored as one row
+func memberKey(key string, member string) string {
+	return key + ":" + member
+}
+
+// memberPattern is helper func to get the like pattern of all members of set
+func memberPattern(key string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(key)
+	return escaped + ":%"
+}
+
+// AddMember will add the member to the set with specific key
+// the ttl is applied to the member, so every member is expired separately
+func (s *SQLStore) AddMember(key string, member string, ttl time.Duration) error {
+	return s.Put(memberKey(key, member), member, ttl)
+}
+
+// RemoveMember will remove the member from the set with specific key
+func (s *SQLStore) RemoveMember(key string, member string) error {
+	return s.Delete(memberKey(key, member))
+}
+
+const getMembersQuery = `
+	SELECT value FROM guard_session 
+	WHERE session_key LIKE ? AND (expired_at IS NULL OR expired_at > ?)
+`
+
+// Members will return all members of the set with specific key
+// empty slice will be returned if the set is not exist or already expired
+func (s *SQLStore) Members(key string) ([]string, error) {
+	rows, err := s.dbContract.Query(getMembersQuery, memberPattern(key), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]string, 0)
+	for rows.Next() {
+		var member string
+		err = rows.Scan(&member)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+const sweepSessionQuery = `DELETE FROM guard_session WHERE expired_at IS NOT NULL AND expired_at <= ?`
+
+// Sweep will delete all expired session from the `guard_session` table
+func (s *SQLStore) Sweep() error {
+	_, err := s.dbContract.Exec(sweepSessionQuery, time.Now())
+	return err
+}
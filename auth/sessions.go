@@ -0,0 +1,407 @@
+package auth
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/auth/token"
+	"github.com/dhanarJkusuma/guardian/event"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+const (
+	sessionPrefix      = "guardian:session:"
+	sessionTokenPrefix = "guardian:session_token:"
+	userSessionPrefix  = "guardian:user_session_set:"
+
+	// lastSeenInterval is the minimum interval to update the last seen time of session,
+	// so the session store isn't written on every request
+	lastSeenInterval = time.Minute
+)
+
+// Session represents the login session of user
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"user_id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+
+	// ImpersonatorID is the id of admin that impersonates the user, it's 0 for the normal session
+	ImpersonatorID int64 `json:"impersonator_id,omitempty"`
+}
+
+// sessionRecord is the session that stored in the session store
+// it keeps the token and refresh token family, so the session can be revoked by id.
+// the cookie session also keeps the csrf token
+type sessionRecord struct {
+	Session
+	Token     string `json:"token"`
+	FamilyID  string `json:"family_id,omitempty"`
+	CSRFToken string `json:"csrf_token,omitempty"`
+
+	// ImpersonatorSessionID is the session of admin that started the impersonation by cookie
+	ImpersonatorSessionID string `json:"impersonator_session_id,omitempty"`
+}
+
+// sessionParams contains the metadata of session that will be created by newSession
+type sessionParams struct {
+	isCookie  bool
+	ipAddress string
+	userAgent string
+	familyID  string
+	refreshed bool
+
+	impersonatorID        int64
+	impersonatorSessionID string
+}
+
+// newSession will generate the token and create the login session for user
+func (a *Auth) newSession(user *schema.User, params sessionParams) (string, error) {
+	record, err := a.createSessionRecord(user, params)
+	if err != nil {
+		return "", err
+	}
+	return record.Token, nil
+}
+
+// createSessionRecord will generate the token and store the session record of user
+// the session is indexed by user id, so it can be listed and revoked by ListSessions, RevokeSession and RevokeAllSessions.
+// if jwt strategy is set, the session id is used as jwt id and the token isn't stored in the session store
+func (a *Auth) createSessionRecord(user *schema.User, params sessionParams) (*sessionRecord, error) {
+	now := time.Now()
+	record := sessionRecord{
+		Session: Session{
+			ID:         uuid.NewV4().String(),
+			UserID:     user.ID,
+			IPAddress:  params.ipAddress,
+			UserAgent:  params.userAgent,
+			CreatedAt:  now,
+			LastSeenAt: now,
+
+			ImpersonatorID: params.impersonatorID,
+		},
+		FamilyID: params.familyID,
+
+		ImpersonatorSessionID: params.impersonatorSessionID,
+	}
+	if a.expiredInSeconds > 0 {
+		record.ExpiredAt = now.Add(a.sessionTTL())
+	}
+
+	var err error
+	if params.isCookie && a.csrf.Enabled {
+		record.CSRFToken, err = randomToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.jwtStrategy != nil {
+		record.Token, err = a.signJWT(user, &record)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if params.isCookie {
+			record.Token = a.tokenStrategy.GenerateCookie()
+		} else {
+			record.Token = a.tokenStrategy.GenerateToken()
+		}
+		err = a.createSession(record.Token, record.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = a.putJSON(sessionPrefix+record.ID, record, a.recordTTL(&record))
+	if err != nil {
+		return nil, err
+	}
+	err = a.indexSession(user.ID, record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !params.refreshed && params.impersonatorID == 0 {
+		a.publish(event.LoginSucceeded{
+			UserID:     user.ID,
+			SessionID:  record.ID,
+			IPAddress:  record.IPAddress,
+			UserAgent:  record.UserAgent,
+			OccurredAt: now,
+		})
+	}
+	return &record, nil
+}
+
+// createSession will store the session id with specific token in the session store
+// the token is stored in its own namespace, so it can't be used to read the other keys of session store
+func (a *Auth) createSession(token string, sessionID string) error {
+	return a.sessionStore.Put(
+		sessionTokenPrefix+token,
+		sessionID,
+		a.sessionTTL(),
+	)
+}
+
+// recordTTL will return the remaining lifetime of session record
+// the record of session that issued by refresh token is kept until the refresh token is expired,
+// so the refresh token family can be revoked by RevokeSession and RevokeAllSessions
+func (a *Auth) recordTTL(record *sessionRecord) time.Duration {
+	expiredAt := record.ExpiredAt
+	if record.FamilyID != "" && a.refreshInSeconds > 0 {
+		expiredAt = record.CreatedAt.Add(a.refreshTTL())
+	}
+	if expiredAt.IsZero() {
+		return 0
+	}
+	return time.Until(expiredAt)
+}
+
+// indexTTL will return the lifetime of session index, it follows the longest lifetime of session record
+func (a *Auth) indexTTL() time.Duration {
+	if a.expiredInSeconds <= 0 {
+		return 0
+	}
+	if a.refreshInSeconds > a.expiredInSeconds {
+		return a.refreshTTL()
+	}
+	return a.sessionTTL()
+}
+
+// userSessionKey will return the key of session index of user
+func userSessionKey(userID int64) string {
+	return userSessionPrefix + strconv.FormatInt(userID, 10)
+}
+
+// getUserSessionIDs will return the session ids of user from the session index
+func (a *Auth) getUserSessionIDs(userID int64) ([]string, error) {
+	return a.sessionStore.Members(userSessionKey(userID))
+}
+
+// indexSession will add the session id to the session index of user
+// the index is a set in the session store, so the concurrent logins don't overwrite each other
+func (a *Auth) indexSession(userID int64, sessionID string) error {
+	return a.sessionStore.AddMember(userSessionKey(userID), sessionID, a.indexTTL())
+}
+
+// unindexSession will remove the session id from the session index of user
+func (a *Auth) unindexSession(userID int64, sessionID string) error {
+	return a.sessionStore.RemoveMember(userSessionKey(userID), sessionID)
+}
+
+// getSessionRecord will get the session record by session id
+func (a *Auth) getSessionRecord(sessionID string) (*sessionRecord, error) {
+	var record sessionRecord
+	err := a.getJSON(sessionPrefix+sessionID, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// lookupSession will find the session record by token
+// the jwt token is trusted by its claims, so the session record isn't read from the session store,
+// only the denylist is checked if it's enabled
+func (a *Auth) lookupSession(sessionToken string) (*sessionRecord, error) {
+	if a.jwtStrategy != nil && token.IsJWT(sessionToken) {
+		claims, err := a.verifyJWT(sessionToken)
+		if err != nil {
+			return nil, err
+		}
+		return claimsSession(claims, sessionToken), nil
+	}
+
+	sessionID, err := a.sessionStore.Get(sessionTokenPrefix + sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	return a.getSessionRecord(sessionID)
+}
+
+// verifySession will find the session record by token and update the last seen time of session
+// the last seen time of jwt session isn't updated, so the jwt token is verified without writing to the session store
+func (a *Auth) verifySession(sessionToken string) (*sessionRecord, error) {
+	record, err := a.lookupSession(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	if a.jwtStrategy != nil && token.IsJWT(sessionToken) {
+		return record, nil
+	}
+
+	now := time.Now()
+	if now.Sub(record.LastSeenAt) >= lastSeenInterval {
+		record.LastSeenAt = now
+		err = a.putJSON(sessionPrefix+record.ID, record, a.recordTTL(record))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// revokeToken will make the token can't be used anymore
+func (a *Auth) revokeToken(sessionToken string) error {
+	if a.jwtStrategy != nil && token.IsJWT(sessionToken) {
+		return a.revokeJWT(sessionToken)
+	}
+	return a.sessionStore.Delete(sessionTokenPrefix + sessionToken)
+}
+
+// revokeSession will revoke the login session by token
+// if revokeFamily is true, the refresh token family that issued the session will be revoked too
+func (a *Auth) revokeSession(sessionToken string, revokeFamily bool) error {
+	record, err := a.lookupSession(sessionToken)
+	if err != nil {
+		if err == session.ErrSessionNotFound || token.IsJWT(sessionToken) {
+			// the session is already expired or revoked
+			return nil
+		}
+		return err
+	}
+
+	// the jwt session is found by its claims, the stored record contains the refresh token family
+	if a.jwtStrategy != nil && token.IsJWT(sessionToken) {
+		stored, err := a.getSessionRecord(record.ID)
+		switch err {
+		case nil:
+			record = stored
+		case session.ErrSessionNotFound:
+		default:
+			return err
+		}
+	}
+	return a.revokeSessionRecord(record, revokeFamily)
+}
+
+// revokeSessionRecord will revoke the token and remove the session from the session store and the session index
+func (a *Auth) revokeSessionRecord(record *sessionRecord, revokeFamily bool) error {
+	err := a.revokeToken(record.Token)
+	if err != nil {
+		return err
+	}
+
+	if record.ID != "" {
+		err = a.sessionStore.Delete(sessionPrefix + record.ID)
+		if err != nil {
+			return err
+		}
+		err = a.unindexSession(record.UserID, record.ID)
+		if err != nil {
+			return err
+		}
+		a.publish(event.SessionRevoked{
+			UserID:     record.UserID,
+			SessionID:  record.ID,
+			OccurredAt: time.Now(),
+		})
+	}
+
+	if revokeFamily && record.FamilyID != "" {
+		return a.deleteRefreshFamily(record.FamilyID)
+	}
+	return nil
+}
+
+// ListSessions will return all active sessions of user ordered by the created time
+// the expired sessions are removed from the session index
+func (a *Auth) ListSessions(userID int64) ([]Session, error) {
+	ids, err := a.getUserSessionIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		record, err := a.getSessionRecord(id)
+		if err != nil {
+			if err == session.ErrSessionNotFound {
+				err = a.unindexSession(userID, id)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, record.Session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession will revoke the login session by session id
+// the refresh token family that issued the session will be revoked too.
+// ErrTokenNotRevocable will be returned for jwt session if the denylist is disabled, the session is kept
+func (a *Auth) RevokeSession(sessionID string) error {
+	record, err := a.getSessionRecord(sessionID)
+	if err != nil {
+		return err
+	}
+	return a.revokeSessionRecord(record, true)
+}
+
+// RevokeAllSessions will revoke all login sessions of user except the session with exceptCurrent id
+// It can be used to logout user from every device, e.g. when the password has been changed.
+// Use empty exceptCurrent to revoke all sessions.
+// the jwt sessions are kept if the denylist is disabled, then ErrTokenNotRevocable will be returned after the other sessions are revoked
+func (a *Auth) RevokeAllSessions(userID int64, exceptCurrent string) error {
+	ids, err := a.getUserSessionIDs(userID)
+	if err != nil {
+		return err
+	}
+
+	var notRevocable bool
+	for _, id := range ids {
+		if id == exceptCurrent {
+			continue
+		}
+		record, err := a.getSessionRecord(id)
+		if err != nil {
+			if err == session.ErrSessionNotFound {
+				err = a.unindexSession(userID, id)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		err = a.revokeSessionRecord(record, true)
+		if err == ErrTokenNotRevocable {
+			notRevocable = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if notRevocable {
+		return ErrTokenNotRevocable
+	}
+	return nil
+}
+
+// GetSessionID is helper function to get the current session id by request
+// You should using middleware authentication before call this function
+// If not it'll return empty string
+func GetSessionID(r *http.Request) string {
+	sessionID, ok := r.Context().Value(SessionID).(string)
+	if !ok {
+		return ""
+	}
+	return sessionID
+}
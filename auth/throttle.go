@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+const (
+	// the attempt counter, the lockout, and the progressive delay are stored by the kind prefix and the subject,
+	// so the identifier of login can't be used to build the key of other kind
+	attemptCountPrefix = "guardian:attempts:count:"
+	attemptLockPrefix  = "guardian:attempts:lock:"
+	attemptDelayPrefix = "guardian:attempts:delay:"
+
+	// the subject is the hashed identifier or the ip address
+	attemptUserSubject = "user:"
+	attemptIPSubject   = "ip:"
+
+	defaultThrottleWindow  = 15 * time.Minute
+	defaultLockoutDuration = 15 * time.Minute
+)
+
+// ThrottleOptions contains configuration for brute-force protection in Authenticate
+// the failed attempts are counted per identifier and per ip address in the window that starts from the first failed attempt,
+// the throttling is disabled if both MaxAttempts and MaxAttemptsPerIP <= 0
+type ThrottleOptions struct {
+	// MaxAttempts is the number of failed attempts of one identifier in the window before it's locked
+	MaxAttempts int
+
+	// MaxAttemptsPerIP is the number of failed attempts from one ip address in the window before it's locked
+	// the ip address is taken from LoginParams.IPAddress
+	MaxAttemptsPerIP int
+
+	// Window is the duration of counting window, default is 15 minutes
+	Window time.Duration
+
+	// LockoutDuration is the duration of lockout, default is 15 minutes
+	LockoutDuration time.Duration
+
+	// DelayAfter is the number of failed attempts before the progressive delay is applied,
+	// the delay starts from BaseDelay and doubled on every failed attempt until MaxDelay.
+	// the login within the delay is rejected by *ErrLoginDelayed.
+	// the progressive delay is disabled if BaseDelay <= 0
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// enabled will check the throttling is enabled or not
+func (t *ThrottleOptions) enabled() bool {
+	return t.MaxAttempts > 0 || t.MaxAttemptsPerIP > 0
+}
+
+// window will return the duration of counting window
+func (t *ThrottleOptions) window() time.Duration {
+	if t.Window <= 0 {
+		return defaultThrottleWindow
+	}
+	return t.Window
+}
+
+// lockoutDuration will return the duration of lockout
+func (t *ThrottleOptions) lockoutDuration() time.Duration {
+	if t.LockoutDuration <= 0 {
+		return defaultLockoutDuration
+	}
+	return t.LockoutDuration
+}
+
+// delay will return the progressive delay by the number of failed attempts
+func (t *ThrottleOptions) delay(failures int) time.Duration {
+	if t.BaseDelay <= 0 || failures < t.DelayAfter || failures == 0 {
+		return 0
+	}
+	delay := t.BaseDelay
+	for i := t.DelayAfter; i < failures; i++ {
+		delay *= 2
+		if t.MaxDelay > 0 && delay >= t.MaxDelay {
+			return t.MaxDelay
+		}
+	}
+	return delay
+}
+
+// ErrAccountLocked is returned by Authenticate when the identifier or ip address has too many failed attempts
+// the login is rejected until UnlockAt, or until it's unlocked by UnlockUser
+type ErrAccountLocked struct {
+	UnlockAt time.Time
+}
+
+func (e *ErrAccountLocked) Error() string {
+	return fmt.Sprintf("account is locked until %s", e.UnlockAt.Format(time.RFC3339))
+}
+
+// ErrLoginDelayed is returned by Authenticate when the progressive delay after the failed attempts isn't passed yet
+// the login is rejected until RetryAt, the attempt isn't counted as failed attempt
+type ErrLoginDelayed struct {
+	RetryAt time.Time
+}
+
+func (e *ErrLoginDelayed) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry after %s", e.RetryAt.Format(time.RFC3339))
+}
+
+// attemptSubjects will return the subjects of attempt counters by login params
+// the empty identifier or ip address isn't counted, so it can't lock the other logins without them
+func attemptSubjects(params LoginParams) (string, string) {
+	userSubject := ""
+	if normalizeIdentifier(params.Identifier) != "" {
+		userSubject = identifierSubject(params.Identifier)
+	}
+	ipSubject := ""
+	if params.IPAddress != "" {
+		ipSubject = attemptIPSubject + params.IPAddress
+	}
+	return userSubject, ipSubject
+}
+
+// identifierSubject will return the subject of login identifier, the identifier is hashed after it's normalized
+func identifierSubject(identifier string) string {
+	return attemptUserSubject + hashToken(normalizeIdentifier(identifier))
+}
+
+// normalizeIdentifier is helper func to normalize the login identifier, so `John@mail.com` and `john@mail.com` use the same counter
+func normalizeIdentifier(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// getAttemptTime will get the lockout or retry time of the subject that stored by the kind prefix
+// zero time will be returned if the subject is empty or the key doesn't exist
+func (a *Auth) getAttemptTime(prefix, subject string) (time.Time, error) {
+	if subject == "" {
+		return time.Time{}, nil
+	}
+
+	value, err := a.sessionStore.Get(prefix + subject)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// putAttemptTime will store the lockout or retry time of the subject by the kind prefix until the time is passed
+func (a *Auth) putAttemptTime(prefix, subject string, until time.Time) error {
+	return a.sessionStore.Put(prefix+subject, until.Format(time.RFC3339Nano), time.Until(until))
+}
+
+// latestAttemptTime will return the latest time of identifier and ip address that stored by the kind prefix
+func (a *Auth) latestAttemptTime(prefix, userSubject, ipSubject string) (time.Time, error) {
+	userTime, err := a.getAttemptTime(prefix, userSubject)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ipTime, err := a.getAttemptTime(prefix, ipSubject)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ipTime.After(userTime) {
+		return ipTime, nil
+	}
+	return userTime, nil
+}
+
+// authenticateThrottle will validate the login params with throttling
+// the failed attempts are counted, they're reset by Authenticate after every factor of login is verified
+func (a *Auth) authenticateThrottle(params LoginParams) (*schema.User, error) {
+	err := a.checkThrottle(params)
+	if err != nil {
+		return nil, err
+	}
+
+	loggedUser, err := a.authenticate(params)
+	switch err {
+	case nil:
+		return loggedUser, nil
+	case ErrInvalidUserLogin, ErrInvalidPasswordLogin:
+		lockErr := a.recordFailedLogin(params)
+		if lockErr != nil {
+			return nil, lockErr
+		}
+	}
+	return nil, err
+}
+
+// checkThrottle will reject the login when the identifier or ip address is locked,
+// or when the progressive delay after the last failed attempt isn't passed yet
+func (a *Auth) checkThrottle(params LoginParams) error {
+	userSubject, ipSubject := attemptSubjects(params)
+	now := time.Now()
+
+	unlockAt, err := a.latestAttemptTime(attemptLockPrefix, userSubject, ipSubject)
+	if err != nil {
+		return err
+	}
+	if unlockAt.After(now) {
+		return &ErrAccountLocked{UnlockAt: unlockAt}
+	}
+
+	retryAt, err := a.latestAttemptTime(attemptDelayPrefix, userSubject, ipSubject)
+	if err != nil {
+		return err
+	}
+	if retryAt.After(now) {
+		return &ErrLoginDelayed{RetryAt: retryAt}
+	}
+	return nil
+}
+
+// recordFailure will increment the attempt counter of subject atomically and lock it when the attempts exceed maxAttempts
+// the unlock time will be returned if it's locked, otherwise the progressive delay is stored for the next attempt
+func (a *Auth) recordFailure(subject string, maxAttempts int) (time.Time, error) {
+	if subject == "" || maxAttempts <= 0 {
+		return time.Time{}, nil
+	}
+	failures, err := a.sessionStore.Incr(attemptCountPrefix+subject, a.throttle.window())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	if failures >= int64(maxAttempts) {
+		unlockAt := now.Add(a.throttle.lockoutDuration())
+		err = a.putAttemptTime(attemptLockPrefix, subject, unlockAt)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// the attempts are counted from zero after the lockout
+		return unlockAt, a.sessionStore.Delete(attemptCountPrefix + subject)
+	}
+
+	if delay := a.throttle.delay(int(failures)); delay > 0 {
+		err = a.putAttemptTime(attemptDelayPrefix, subject, now.Add(delay))
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Time{}, nil
+}
+
+// recordFailedLogin will record the failed login per identifier and per ip address
+// ErrAccountLocked will be returned if the failed login makes the identifier or ip address locked
+func (a *Auth) recordFailedLogin(params LoginParams) error {
+	userSubject, ipSubject := attemptSubjects(params)
+	userUnlockAt, err := a.recordFailure(userSubject, a.throttle.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	ipUnlockAt, err := a.recordFailure(ipSubject, a.throttle.MaxAttemptsPerIP)
+	if err != nil {
+		return err
+	}
+
+	unlockAt := userUnlockAt
+	if ipUnlockAt.After(unlockAt) {
+		unlockAt = ipUnlockAt
+	}
+	if unlockAt.After(time.Now()) {
+		return &ErrAccountLocked{UnlockAt: unlockAt}
+	}
+	return nil
+}
+
+// clearAttempts will delete the attempt counter, the lockout, and the progressive delay of the subject
+func (a *Auth) clearAttempts(subject string) error {
+	if subject == "" {
+		return nil
+	}
+	for _, prefix := range []string{attemptCountPrefix, attemptLockPrefix, attemptDelayPrefix} {
+		err := a.sessionStore.Delete(prefix + subject)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetFailedLogin will reset the failed attempts of identifier after successful login
+func (a *Auth) resetFailedLogin(params LoginParams) error {
+	if !a.throttle.enabled() {
+		return nil
+	}
+	userSubject, _ := attemptSubjects(params)
+	return a.clearAttempts(userSubject)
+}
+
+// UnlockUser will reset the failed attempts and remove the lockout of user
+// both email and username of user are unlocked, so it works with every LoginMethod
+func (a *Auth) UnlockUser(user *schema.User) error {
+	for _, identifier := range []string{user.Email, user.Username} {
+		if identifier == "" {
+			continue
+		}
+		err := a.clearAttempts(identifierSubject(identifier))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnlockIPAddress will reset the failed attempts and remove the lockout of ip address
+func (a *Auth) UnlockIPAddress(ipAddress string) error {
+	return a.clearAttempts(attemptIPSubject + ipAddress)
+}
@@ -0,0 +1,320 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrInvalidJWT           = errors.New("invalid jwt token")
+	ErrJWTExpired           = errors.New("jwt token is expired")
+	ErrJWTNotValidYet       = errors.New("jwt token is not valid yet")
+	ErrUnknownJWTKey        = errors.New("unknown jwt key")
+	ErrInvalidJWTKey        = errors.New("invalid jwt key")
+	ErrUnsupportedAlgorithm = errors.New("unsupported jwt algorithm")
+)
+
+// JWTKey represents the key that used to sign and verify jwt token
+// HS256 key uses Secret, RS256 key uses *rsa.PrivateKey and *rsa.PublicKey,
+// EdDSA key uses ed25519.PrivateKey and ed25519.PublicKey.
+// PrivateKey can be empty if the key is only used to verify the token
+type JWTKey struct {
+	ID        string
+	Algorithm string
+
+	Secret     []byte
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// validate will check the key material is match with the algorithm
+func (k *JWTKey) validate() error {
+	if k == nil || k.ID == "" {
+		return ErrInvalidJWTKey
+	}
+	switch k.Algorithm {
+	case AlgorithmHS256:
+		if len(k.Secret) == 0 {
+			return ErrInvalidJWTKey
+		}
+	case AlgorithmRS256:
+		if _, ok := k.PublicKey.(*rsa.PublicKey); !ok {
+			if privateKey, ok := k.PrivateKey.(*rsa.PrivateKey); ok {
+				k.PublicKey = &privateKey.PublicKey
+				return nil
+			}
+			return ErrInvalidJWTKey
+		}
+	case AlgorithmEdDSA:
+		if _, ok := k.PublicKey.(ed25519.PublicKey); !ok {
+			if privateKey, ok := k.PrivateKey.(ed25519.PrivateKey); ok {
+				k.PublicKey = privateKey.Public()
+				return nil
+			}
+			return ErrInvalidJWTKey
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
+
+// sign will sign the signing input with the key
+func (k *JWTKey) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case AlgorithmRS256:
+		privateKey, ok := k.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidJWTKey
+		}
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	case AlgorithmEdDSA:
+		privateKey, ok := k.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidJWTKey
+		}
+		return ed25519.Sign(privateKey, input), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// verify will verify the signature of signing input with the key
+func (k *JWTKey) verify(input, signature []byte) bool {
+	switch k.Algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case AlgorithmRS256:
+		publicKey, ok := k.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case AlgorithmEdDSA:
+		publicKey, ok := k.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(publicKey, input, signature)
+	}
+	return false
+}
+
+// Claims represents the payload of jwt token that issued by guardian
+// ImpersonatorID and CSRFToken are only set for the impersonated session and the cookie session with csrf protection
+type Claims struct {
+	ID        string   `json:"jti"`
+	Subject   string   `json:"sub"`
+	UserID    int64    `json:"uid"`
+	Roles     []string `json:"roles,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+
+	ImpersonatorID int64  `json:"imp,omitempty"`
+	CSRFToken      string `json:"csrf,omitempty"`
+}
+
+// ExpiredTime will return the expiration time of the claims
+func (c *Claims) ExpiredTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// JWTStrategy will sign and verify stateless jwt token
+// It can hold many keys at the same time to support key rotation,
+// the token is always signed by the signing key, and verified by the key that referenced by `kid` header
+type JWTStrategy struct {
+	Issuer string
+	Leeway time.Duration
+
+	mu           sync.RWMutex
+	keys         map[string]*JWTKey
+	signingKeyID string
+}
+
+// NewJWTStrategy acts as constructor with the issuer and the signing key
+func NewJWTStrategy(issuer string, signingKey *JWTKey) (*JWTStrategy, error) {
+	strategy := &JWTStrategy{
+		Issuer: issuer,
+		keys:   make(map[string]*JWTKey),
+	}
+	err := strategy.AddKey(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	strategy.signingKeyID = signingKey.ID
+	return strategy, nil
+}
+
+// AddKey will register the key, the key can be used to verify the token which has the same `kid`
+func (j *JWTStrategy) AddKey(key *JWTKey) error {
+	err := key.validate()
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.keys[key.ID] = key
+	j.mu.Unlock()
+	return nil
+}
+
+// SetSigningKey will rotate the signing key with the registered key
+// the old key is still used to verify the token until it's removed by RemoveKey
+func (j *JWTStrategy) SetSigningKey(keyID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[keyID]
+	if !ok {
+		return ErrUnknownJWTKey
+	}
+	if key.Algorithm != AlgorithmHS256 && key.PrivateKey == nil {
+		return ErrInvalidJWTKey
+	}
+	j.signingKeyID = keyID
+	return nil
+}
+
+// RemoveKey will remove the key, the token signed by this key can't be verified anymore
+// the signing key can't be removed
+func (j *JWTStrategy) RemoveKey(keyID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if keyID == j.signingKeyID {
+		return ErrInvalidJWTKey
+	}
+	delete(j.keys, keyID)
+	return nil
+}
+
+// Sign will create a new signed jwt token with the claims
+// the issuer and issued at will be filled if it's empty
+func (j *JWTStrategy) Sign(claims Claims) (string, error) {
+	j.mu.RLock()
+	key := j.keys[j.signingKeyID]
+	j.mu.RUnlock()
+
+	if claims.Issuer == "" {
+		claims.Issuer = j.Issuer
+	}
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = time.Now().Unix()
+	}
+
+	header, err := json.Marshal(jwtHeader{
+		Algorithm: key.Algorithm,
+		Type:      "JWT",
+		KeyID:     key.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify will verify the signature, issuer, and the time of jwt token, then return the claims
+func (j *JWTStrategy) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidJWT
+	}
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+
+	j.mu.RLock()
+	key, ok := j.keys[header.KeyID]
+	j.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownJWTKey
+	}
+	// the algorithm must be pinned by the key to prevent algorithm confusion
+	if header.Algorithm != key.Algorithm {
+		return nil, ErrInvalidJWT
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidJWT
+	}
+
+	var claims Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if j.Issuer != "" && claims.Issuer != j.Issuer {
+		return nil, ErrInvalidJWT
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(j.Leeway)) {
+		return nil, ErrJWTExpired
+	}
+	if claims.NotBefore != 0 && now.Add(j.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, ErrJWTNotValidYet
+	}
+	return &claims, nil
+}
+
+// decodeSegment is helper func to decode base64url json segment
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// IsJWT will check the token has jwt format or not
+// jwt header is always json object, so the encoded token is started with `eyJ`
+func IsJWT(token string) bool {
+	return strings.HasPrefix(token, "eyJ") && strings.Count(token, ".") == 2
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/auth/session"
+	"github.com/dhanarJkusuma/guardian/schema"
+)
+
+const userTokenPrefix = "guardian:user_token:"
+
+// userTokenRecord represents the single-use token of user, e.g. password reset token
+// the token is stored as hash in the session store, and only one token per type can be used by user at the same time
+type userTokenRecord struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// randomToken will generate url safe random token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken will hash the token, so the plain token isn't stored in the session store
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userTokenKey will return the key of token record
+func userTokenKey(tokenType NotificationType, tokenHash string) string {
+	return userTokenPrefix + string(tokenType) + ":" + tokenHash
+}
+
+// userTokenIndexKey will return the key that keeps the current token hash of user
+func userTokenIndexKey(tokenType NotificationType, userID int64) string {
+	return userTokenPrefix + string(tokenType) + ":user:" + strconv.FormatInt(userID, 10)
+}
+
+// issueUserToken will create a new token of user and deliver it by Notifier
+// the previous token of user with the same type can't be used anymore
+func (a *Auth) issueUserToken(tokenType NotificationType, user *schema.User, ttl time.Duration) error {
+	if a.notifier == nil {
+		return ErrNoNotifier
+	}
+
+	// invalidate the previous token
+	indexKey := userTokenIndexKey(tokenType, user.ID)
+	previousHash, err := a.sessionStore.Get(indexKey)
+	if err == nil {
+		err = a.sessionStore.Delete(userTokenKey(tokenType, previousHash))
+	}
+	if err != nil && err != session.ErrSessionNotFound {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	tokenHash := hashToken(token)
+	expiredAt := time.Now().Add(ttl)
+	err = a.putJSON(userTokenKey(tokenType, tokenHash), userTokenRecord{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiredAt: expiredAt,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+	err = a.sessionStore.Put(indexKey, tokenHash, ttl)
+	if err != nil {
+		return err
+	}
+
+	return a.notifier.Notify(Notification{
+		Type:      tokenType,
+		User:      user,
+		Token:     token,
+		ExpiredAt: expiredAt,
+	})
+}
+
+// takeUserToken will consume the token atomically and return the token record, so the concurrent requests can't use the same token.
+// session.ErrSessionNotFound will be returned if the token is invalid, expired, or already used
+func (a *Auth) takeUserToken(tokenType NotificationType, token string) (*userTokenRecord, error) {
+	data, err := a.sessionStore.Take(userTokenKey(tokenType, hashToken(token)))
+	if err != nil {
+		return nil, err
+	}
+	var record userTokenRecord
+	err = json.Unmarshal([]byte(data), &record)
+	if err != nil {
+		return nil, err
+	}
+	if !record.ExpiredAt.After(time.Now()) {
+		return nil, session.ErrSessionNotFound
+	}
+	return &record, nil
+}
+
+// restoreUserToken will put back the token that consumed by takeUserToken, so user can retry with the same token
+// e.g. when the new password is rejected by the password policy
+func (a *Auth) restoreUserToken(tokenType NotificationType, token string, record *userTokenRecord) error {
+	ttl := time.Until(record.ExpiredAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return a.putJSON(userTokenKey(tokenType, hashToken(token)), record, ttl)
+}
+
+// clearUserTokenIndex will delete the current token hash of user after the token is used
+func (a *Auth) clearUserTokenIndex(tokenType NotificationType, userID int64) error {
+	return a.sessionStore.Delete(userTokenIndexKey(tokenType, userID))
+}
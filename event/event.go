@@ -0,0 +1,137 @@
+package event
+
+import (
+	"sync"
+)
+
+// Event is the authentication lifecycle event that published by guardian
+// the subscriber can use type switch to get the typed event, e.g. event.LoginSucceeded
+type Event interface {
+	EventName() string
+}
+
+// Handler is the subscriber function that receives the published event
+type Handler func(e Event)
+
+// Bus is the event bus that delivers the published event to the subscribers.
+// the synchronous bus calls the subscribers in the publisher goroutine,
+// and the asynchronous bus calls the subscribers in the worker goroutines.
+// the panic of subscriber is recovered, so it can't break the authentication flow
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+
+	queue   chan Event
+	wg      sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// NewBus will create the synchronous event bus
+// Publish will return after all subscribers have received the event
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// NewAsyncBus will create the asynchronous event bus with buffered queue and worker goroutines
+// Publish will block when the queue is full, and Close must be called to deliver the queued events before exit
+func NewAsyncBus(bufferSize, workers int) *Bus {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	bus := NewBus()
+	bus.queue = make(chan Event, bufferSize)
+	bus.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go bus.work()
+	}
+	return bus
+}
+
+// Subscribe will register the handler for the event name, e.g. event.LoginSucceededEvent
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// SubscribeAll will register the handler for all events
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// Publish will deliver the event to the subscribers
+// nil bus is valid and the event is discarded, the event is also discarded when the bus is closed
+func (b *Bus) Publish(e Event) {
+	if b == nil || e == nil {
+		return
+	}
+	if b.queue == nil {
+		b.dispatch(e)
+		return
+	}
+
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+	if b.closed {
+		return
+	}
+	b.queue <- e
+}
+
+// Close will stop the asynchronous bus after all queued events have been delivered
+// it does nothing for the synchronous bus
+func (b *Bus) Close() {
+	if b == nil || b.queue == nil {
+		return
+	}
+
+	b.closeMu.Lock()
+	if b.closed {
+		b.closeMu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.closeMu.Unlock()
+
+	b.wg.Wait()
+}
+
+// work will deliver the queued events until the queue is closed
+func (b *Bus) work() {
+	defer b.wg.Done()
+	for e := range b.queue {
+		b.dispatch(e)
+	}
+}
+
+// dispatch will call the subscribers of event
+func (b *Bus) dispatch(e Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[e.EventName()])+len(b.all))
+	handlers = append(handlers, b.handlers[e.EventName()]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		call(handler, e)
+	}
+}
+
+// call will call the handler and recover the panic of handler
+func call(handler Handler, e Event) {
+	defer func() {
+		recover()
+	}()
+	handler(e)
+}
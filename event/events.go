@@ -0,0 +1,153 @@
+package event
+
+import (
+	"time"
+)
+
+const (
+	LoginSucceededEvent       = "login.succeeded"
+	LoginFailedEvent          = "login.failed"
+	SessionRevokedEvent       = "session.revoked"
+	UserRegisteredEvent       = "user.registered"
+	PasswordChangedEvent      = "password.changed"
+	RoleAssignedEvent         = "role.assigned"
+	RoleRevokedEvent          = "role.revoked"
+	RoleExpiredEvent          = "role.expired"
+	PermissionGrantedEvent    = "permission.granted"
+	PermissionRevokedEvent    = "permission.revoked"
+	ImpersonationStartedEvent = "impersonation.started"
+	ImpersonationStoppedEvent = "impersonation.stopped"
+)
+
+// LoginSucceeded is published when the login session of user is created,
+// it isn't published when the session is rotated by refresh token
+type LoginSucceeded struct {
+	UserID     int64
+	SessionID  string
+	IPAddress  string
+	UserAgent  string
+	OccurredAt time.Time
+}
+
+func (LoginSucceeded) EventName() string { return LoginSucceededEvent }
+
+// LoginFailed is published when Authenticate rejects the login params
+// Reason is the returned error, e.g. auth.ErrInvalidPasswordLogin or *auth.ErrAccountLocked
+type LoginFailed struct {
+	Identifier string
+	IPAddress  string
+	UserAgent  string
+	Reason     error
+	OccurredAt time.Time
+}
+
+func (LoginFailed) EventName() string { return LoginFailedEvent }
+
+// SessionRevoked is published when the login session is revoked, e.g. logout or RevokeSession
+type SessionRevoked struct {
+	UserID     int64
+	SessionID  string
+	OccurredAt time.Time
+}
+
+func (SessionRevoked) EventName() string { return SessionRevokedEvent }
+
+// UserRegistered is published when the new user is created by Register or OpenID Connect login
+type UserRegistered struct {
+	UserID     int64
+	Username   string
+	Email      string
+	OccurredAt time.Time
+}
+
+func (UserRegistered) EventName() string { return UserRegisteredEvent }
+
+// PasswordChanged is published when the password of user is changed or reset
+type PasswordChanged struct {
+	UserID     int64
+	OccurredAt time.Time
+}
+
+func (PasswordChanged) EventName() string { return PasswordChangedEvent }
+
+// RoleAssigned is published when the role is assigned to user
+// Domain is empty if the role is assigned globally, ValidFrom and ValidUntil are nil if the role isn't temporary
+type RoleAssigned struct {
+	RoleID     int64
+	RoleName   string
+	UserID     int64
+	Domain     string
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+	OccurredAt time.Time
+}
+
+func (RoleAssigned) EventName() string { return RoleAssignedEvent }
+
+// RoleRevoked is published when the role of user is revoked
+// Domain is empty if the global role is revoked
+type RoleRevoked struct {
+	RoleID     int64
+	RoleName   string
+	UserID     int64
+	Domain     string
+	OccurredAt time.Time
+}
+
+func (RoleRevoked) EventName() string { return RoleRevokedEvent }
+
+// RoleExpired is published when the expired temporary role of user is deleted by the sweeper
+type RoleExpired struct {
+	RoleID     int64
+	RoleName   string
+	UserID     int64
+	Domain     string
+	ExpiredAt  time.Time
+	OccurredAt time.Time
+}
+
+func (RoleExpired) EventName() string { return RoleExpiredEvent }
+
+// PermissionGranted is published when the permission is added to role or denied for role
+// Effect is `allow` or `deny`
+type PermissionGranted struct {
+	RoleID         int64
+	RoleName       string
+	PermissionID   int64
+	PermissionName string
+	Effect         string
+	OccurredAt     time.Time
+}
+
+func (PermissionGranted) EventName() string { return PermissionGrantedEvent }
+
+// PermissionRevoked is published when the permission is removed from role
+type PermissionRevoked struct {
+	RoleID         int64
+	RoleName       string
+	PermissionID   int64
+	PermissionName string
+	OccurredAt     time.Time
+}
+
+func (PermissionRevoked) EventName() string { return PermissionRevokedEvent }
+
+// ImpersonationStarted is published when the admin starts impersonating user
+type ImpersonationStarted struct {
+	ImpersonatorID int64
+	UserID         int64
+	SessionID      string
+	OccurredAt     time.Time
+}
+
+func (ImpersonationStarted) EventName() string { return ImpersonationStartedEvent }
+
+// ImpersonationStopped is published when the impersonated session is stopped
+type ImpersonationStopped struct {
+	ImpersonatorID int64
+	UserID         int64
+	SessionID      string
+	OccurredAt     time.Time
+}
+
+func (ImpersonationStopped) EventName() string { return ImpersonationStoppedEvent }
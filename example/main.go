@@ -74,7 +74,7 @@ func main() {
 		Session: guardian.SessionOptions{
 			CacheClient:      cacheClient,
 			LoginMethod:      auth.LoginEmail,
-			ExpiredInSeconds: int64(24 * time.Hour),
+			ExpiredInSeconds: int64((24 * time.Hour).Seconds()),
 			SessionName:      "_Guardian_Session_",
 		}}).
 		SetSchemaValidation(schemaGuardCfg).
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/dhanarJkusuma/guardian/auth"
+	"github.com/dhanarJkusuma/guardian/auth/oidc"
 	"github.com/dhanarJkusuma/guardian/auth/password"
+	"github.com/dhanarJkusuma/guardian/auth/session"
 	"github.com/dhanarJkusuma/guardian/auth/token"
+	"github.com/dhanarJkusuma/guardian/event"
 	"github.com/dhanarJkusuma/guardian/migration"
 	"github.com/dhanarJkusuma/guardian/schema"
 	"github.com/go-redis/redis"
+	"time"
 )
 
 // Guardian wrap all needed function for authentication in the guardian library
@@ -17,26 +21,84 @@ type Guardian struct {
 	Migration *migration.Migration
 	Auth      *auth.Auth
 
+	// Events is the event bus that publishes the authentication lifecycle events, e.g. event.LoginSucceeded
+	Events *event.Bus
+
 	guardSchema *schema.Schema
+	sweeper     *schema.AssignmentSweeper
 }
 
+// SessionOptions contains configuration for the login session
+// SessionStore is used to store the session, if it's nil then CacheClient will be used as redis session store.
+// if both of them are nil, the session will be stored in the database using `guard_session` table
 type SessionOptions struct {
+	SessionStore     session.SessionStore
 	CacheClient      *redis.Client
 	LoginMethod      auth.LoginMethod
 	SessionName      string
 	ExpiredInSeconds int64
+
+	// RefreshExpiredInSeconds is lifetime of refresh token, refresh token is disabled if the value <= 0
+	RefreshExpiredInSeconds int64
+
+	// JWTDenylist will store the revoked jwt token in the session store, it's only used when jwt strategy is set
+	JWTDenylist bool
+
+	// PasswordResetExpiredInSeconds is lifetime of password reset token, default is 1 hour
+	PasswordResetExpiredInSeconds int64
+
+	// EmailVerification will send email verification token by notifier when user is registered
+	// RequireVerifiedEmail will reject the login of user that hasn't verified the email
+	// EmailVerificationExpiredInSeconds is lifetime of email verification token, default is 24 hours
+	EmailVerification                 bool
+	RequireVerifiedEmail              bool
+	EmailVerificationExpiredInSeconds int64
+
+	// MFAIssuer is the issuer name of TOTP that shown in the authenticator app
+	MFAIssuer string
+
+	// Throttle contains configuration for brute-force protection and account lockout
+	Throttle auth.ThrottleOptions
+
+	// CSRF contains configuration for csrf protection of cookie based authentication
+	CSRF auth.CSRFOptions
+
+	// Cookie contains the attributes of session cookie, e.g. Secure, HttpOnly, SameSite
+	Cookie auth.CookieOptions
+
+	// PasswordPolicy contains the password rules, password history size, and maximum password age
+	PasswordPolicy *password.Policy
+
+	// ImpersonatePermission is the permission name that required to impersonate other user, default is `impersonate_user`
+	ImpersonatePermission string
+
+	// OIDCAutoRegister will create a new user when the external identity isn't linked to any user
+	// OIDCLinkVerifiedEmail will link the external identity to the user with the same verified email
+	OIDCAutoRegister      bool
+	OIDCLinkVerifiedEmail bool
 }
 
 type Options struct {
 	DbConnection *sql.DB
 	SchemaName   string
 	Session      SessionOptions
+
+	// CombiningAlgorithm decides the access when the permission is allowed and denied by user's roles, default is schema.DenyOverrides
+	CombiningAlgorithm schema.CombiningAlgorithm
+
+	// RoleSweepIntervalInSeconds is the interval of deleting the expired temporary roles in the background, the sweeper is disabled if the value <= 0
+	RoleSweepIntervalInSeconds int64
 }
 
 type guardianBuilder struct {
 	guardOpts        *Options
 	tokenStrategy    token.TokenGenerator
 	passwordStrategy password.PasswordGenerator
+	jwtStrategy      *token.JWTStrategy
+	notifier         auth.Notifier
+	oidcProviders    []*oidc.Provider
+	events           *event.Bus
+	errorHandler     auth.ErrorHandler
 	validation       string
 }
 
@@ -59,6 +121,39 @@ func (p *guardianBuilder) SetTokenGenerator(generator token.TokenGenerator) *gua
 	return p
 }
 
+// SetJWTStrategy will set stateless jwt token strategy in the guardian library
+// the login session will be issued as signed jwt token instead of token that stored in the session store
+func (p *guardianBuilder) SetJWTStrategy(strategy *token.JWTStrategy) *guardianBuilder {
+	p.jwtStrategy = strategy
+	return p
+}
+
+// SetNotifier will set notifier that used to deliver the token to user, e.g. password reset token
+func (p *guardianBuilder) SetNotifier(notifier auth.Notifier) *guardianBuilder {
+	p.notifier = notifier
+	return p
+}
+
+// AddOIDCProvider will register OpenID Connect provider that can be used for social login
+func (p *guardianBuilder) AddOIDCProvider(provider *oidc.Provider) *guardianBuilder {
+	p.oidcProviders = append(p.oidcProviders, provider)
+	return p
+}
+
+// SetEventBus will set the event bus that publishes the authentication lifecycle events
+// use event.NewAsyncBus to deliver the events in the background, the synchronous bus is used by default
+func (p *guardianBuilder) SetEventBus(bus *event.Bus) *guardianBuilder {
+	p.events = bus
+	return p
+}
+
+// SetErrorHandler will set the error handler that writes the response when the middleware rejects the request
+// the error is written as RFC 7807 problem+json response by default
+func (p *guardianBuilder) SetErrorHandler(handler auth.ErrorHandler) *guardianBuilder {
+	p.errorHandler = handler
+	return p
+}
+
 // SetPasswordGenerator will set password strategy in the guardian library
 func (p *guardianBuilder) SetPasswordGenerator(generator password.PasswordGenerator) *guardianBuilder {
 	p.passwordStrategy = generator
@@ -85,10 +180,17 @@ func (p *guardianBuilder) Build() *Guardian {
 	}
 
 	validator.Initialize()
+	if p.events == nil {
+		p.events = event.NewBus()
+	}
 	rbac := &Guardian{
+		Events: p.events,
 		guardSchema: &schema.Schema{
 			DbConnection: p.guardOpts.DbConnection,
 			Validator:    validator,
+			Events:       p.events,
+
+			CombiningAlgorithm: p.guardOpts.CombiningAlgorithm,
 		},
 	}
 
@@ -97,12 +199,36 @@ func (p *guardianBuilder) Build() *Guardian {
 		SessionName: p.guardOpts.Session.SessionName,
 		GuardSchema: rbac.guardSchema,
 
+		SessionStore: p.guardOpts.Session.SessionStore,
 		CacheClient:  p.guardOpts.Session.CacheClient,
 		LoginMethod:  p.guardOpts.Session.LoginMethod,
 		ExpiredInSec: p.guardOpts.Session.ExpiredInSeconds,
 
+		RefreshExpiredInSec: p.guardOpts.Session.RefreshExpiredInSeconds,
+
 		TokenStrategy:    p.tokenStrategy,
 		PasswordStrategy: p.passwordStrategy,
+		JWTStrategy:      p.jwtStrategy,
+		JWTDenylist:      p.guardOpts.Session.JWTDenylist,
+		Throttle:         p.guardOpts.Session.Throttle,
+		CSRF:             p.guardOpts.Session.CSRF,
+		Cookie:           p.guardOpts.Session.Cookie,
+		ErrorHandler:     p.errorHandler,
+		PasswordPolicy:   p.guardOpts.Session.PasswordPolicy,
+		MFAIssuer:        p.guardOpts.Session.MFAIssuer,
+
+		Notifier:                  p.notifier,
+		PasswordResetExpiredInSec: p.guardOpts.Session.PasswordResetExpiredInSeconds,
+
+		ImpersonatePermission: p.guardOpts.Session.ImpersonatePermission,
+
+		EmailVerification:             p.guardOpts.Session.EmailVerification,
+		RequireVerifiedEmail:          p.guardOpts.Session.RequireVerifiedEmail,
+		EmailVerificationExpiredInSec: p.guardOpts.Session.EmailVerificationExpiredInSeconds,
+
+		OIDCProviders:         p.oidcProviders,
+		OIDCAutoRegister:      p.guardOpts.Session.OIDCAutoRegister,
+		OIDCLinkVerifiedEmail: p.guardOpts.Session.OIDCLinkVerifiedEmail,
 	})
 
 	// initialize migration module
@@ -118,9 +244,23 @@ func (p *guardianBuilder) Build() *Guardian {
 	// set migration and auth module
 	rbac.Migration = migrationModule
 	rbac.Auth = authModule
+
+	if p.guardOpts.RoleSweepIntervalInSeconds > 0 {
+		rbac.sweeper = schema.NewAssignmentSweeper(
+			rbac.guardSchema.RoleAssignment(nil),
+			time.Duration(p.guardOpts.RoleSweepIntervalInSeconds)*time.Second,
+		)
+	}
 	return rbac
 }
 
+// Close will stop the background sweeper of expired temporary roles
+func (p *Guardian) Close() {
+	if p.sweeper != nil {
+		p.sweeper.Close()
+	}
+}
+
 // GetSchema will return guardian schema that used for do some database operation using guardian library
 func (p *Guardian) GetSchema() *schema.Schema {
 	return p.guardSchema
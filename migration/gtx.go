@@ -64,6 +64,19 @@ func (gtx *GuardTx) Rule(rule *schema.Rule) *schema.Rule {
 	return rule
 }
 
+// Group will inject the databaseTx in the `Group` schema
+func (gtx *GuardTx) Group(group *schema.Group) *schema.Group {
+	if group == nil {
+		group = &schema.Group{
+			Entity: schema.Entity{DBContract: gtx.dbTx},
+		}
+	} else {
+		group.DBContract = gtx.dbTx
+	}
+	group.SetValidator(gtx.validator.Group)
+	return group
+}
+
 // GetTx function will return specific database transaction
 func (gtx *GuardTx) GetTx() *sql.Tx {
 	return gtx.dbTx
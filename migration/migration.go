@@ -2,6 +2,7 @@ package migration
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/dhanarJkusuma/guardian/auth"
@@ -10,7 +11,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
 
 	"github.com/dhanarJkusuma/guardian/schema"
 )
@@ -30,6 +33,9 @@ type indexSchema struct {
 	IndexName string `db:"index_name"`
 }
 
+// indexNamePattern is used to get the index name from create index statement
+var indexNamePattern = regexp.MustCompile("(?i)CREATE\\s+(?:UNIQUE\\s+)?INDEX\\s+`([^`]+)`")
+
 // requiredIndexes is used for check existing required indexes in the database
 var requiredIndexes = map[string]bool{
 	"guard_user_email_idx":                      false,
@@ -37,10 +43,63 @@ var requiredIndexes = map[string]bool{
 	"guard_permission_route_method_idx":         false,
 	"guard_permission_name_idx":                 false,
 	"guard_role_name_idx":                       false,
-	"guard_user_role_role_user_idx":             false,
+	"guard_user_role_role_user_domain_idx":      false,
+	"guard_user_role_valid_until_idx":           false,
 	"guard_role_permission_role_permission_idx": false,
+	"guard_role_child_parent_child_idx":         false,
+	"guard_group_name_idx":                      false,
+	"guard_group_child_parent_child_idx":        false,
+	"guard_group_role_group_role_idx":           false,
+	"guard_user_group_group_user_idx":           false,
+	"guard_resource_acl_entry_idx":              false,
+	"guard_resource_acl_principal_idx":          false,
+	"guard_user_mfa_user_idx":                   false,
+	"guard_user_recovery_code_user_code_idx":    false,
+	"guard_api_key_prefix_idx":                  false,
+	"guard_api_key_user_idx":                    false,
+	"guard_api_key_permission_idx":              false,
+	"guard_user_identity_subject_idx":           false,
+	"guard_user_identity_user_idx":              false,
+	"guard_password_history_user_idx":           false,
+	"guard_audit_log_actor_idx":                 false,
+	"guard_audit_log_user_idx":                  false,
 	"guard_role_guard_rule_idx":                 false,
 	"guard_role_guard_rule_checker_idx":         false,
+	"guard_session_key_idx":                     false,
+	"guard_session_expired_at_idx":              false,
+}
+
+// obsoleteIndex is the index that replaced by the index of current version
+type obsoleteIndex struct {
+	table string
+	name  string
+}
+
+// obsoleteIndexes is used for drop the indexes of older version after the required indexes are created, see dropObsoleteIndexes
+var obsoleteIndexes = []obsoleteIndex{
+	// replaced by guard_user_role_role_user_domain_idx, the role can be assigned to the same user in every domain
+	{table: "guard_user_role", name: "guard_user_role_role_user_idx"},
+}
+
+// columnSchema is the column that added or widened after the table is created by the older version
+// the column is added to the existing table if it doesn't exist,
+// and it's modified by the definition if the length is set and the existing column is shorter, see migrateColumns
+type columnSchema struct {
+	table      string
+	column     string
+	definition string
+	length     int64
+}
+
+// requiredColumns is used for check existing required columns in the tables of older version
+var requiredColumns = []columnSchema{
+	{table: "guard_user", column: "email_verified_at", definition: "TIMESTAMP NULL DEFAULT NULL AFTER active"},
+	{table: "guard_user", column: "password", definition: "VARCHAR(255) NOT NULL", length: 255},
+	{table: "guard_user", column: "password_changed_at", definition: "TIMESTAMP NULL DEFAULT NULL AFTER email_verified_at"},
+	{table: "guard_user_role", column: "domain", definition: "VARCHAR(64) NOT NULL DEFAULT '' AFTER user_id"},
+	{table: "guard_user_role", column: "valid_from", definition: "TIMESTAMP NULL DEFAULT NULL AFTER domain"},
+	{table: "guard_user_role", column: "valid_until", definition: "TIMESTAMP NULL DEFAULT NULL AFTER valid_from"},
+	{table: "guard_role_permission", column: "effect", definition: "VARCHAR(8) NOT NULL DEFAULT 'allow' AFTER permission_id"},
 }
 
 // Migration represent entity that has responsibility for schema migration
@@ -118,27 +177,85 @@ func (m *Migration) migrate(filename string) error {
 }
 
 // Initialize function will create migration for RBAC auth
+// It's safe to be called on the existing database, only the missing tables, columns and indexes are created
 func (m *Migration) Initialize() error {
 	var err error
 	fmt.Println("Migration :: Migrating Schema")
 	err = m.migrate(migrationUp)
 	if err != nil {
-		m.Down()
+		return err
+	}
+
+	fmt.Println("Migration :: Migrating columns")
+	err = m.migrateColumns()
+	if err != nil {
 		return err
 	}
 
 	err = m.validateIndexes()
 	if err != nil {
 		fmt.Println("Migration :: Migrating indexes")
-		err = m.migrate(migrationIndexUp)
+		err = m.migrateIndexes()
+		if err != nil {
+			return err
+		}
+		err = m.validateIndexes()
 		if err != nil {
-			m.Down()
 			return err
 		}
-		return nil
 	}
 
-	return err
+	return m.dropObsoleteIndexes()
+}
+
+// migrateIndexes is helper function to create the indexes that don't exist in the database yet
+// every create index statement is executed separately, so the existing indexes are skipped
+func (m *Migration) migrateIndexes() error {
+	migrationPath := fmt.Sprintf("%s/sql/%s", getCurrentPath(), migrationIndexUp)
+	source, err := openSource(migrationPath)
+	if err != nil {
+		return err
+	}
+
+	existing, err := m.existingIndexes()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, statement := range strings.Split(source, ";") {
+		match := indexNamePattern.FindStringSubmatch(statement)
+		if match == nil || existing[match[1]] {
+			continue
+		}
+		_, err = m.gSchema.DbConnection.ExecContext(ctx, statement)
+		if err != nil {
+			return errors.New(fmt.Sprintf(ErrMigration, fmt.Sprintf("error while creating index %s, %s", match[1], err)))
+		}
+	}
+	return nil
+}
+
+// dropObsoleteIndexes is helper function to drop the indexes of older version that still exist in the database
+// it must be called after the required indexes are created, so the foreign keys are still covered by the new indexes
+func (m *Migration) dropObsoleteIndexes() error {
+	existing, err := m.existingIndexes()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, index := range obsoleteIndexes {
+		if !existing[index.name] {
+			continue
+		}
+		fmt.Printf("Migration :: Dropping obsolete index %s\n", index.name)
+		_, err = m.gSchema.DbConnection.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DROP INDEX `%s`", index.table, index.name))
+		if err != nil {
+			return errors.New(fmt.Sprintf(ErrMigration, fmt.Sprintf("error while dropping index %s, %s", index.name, err)))
+		}
+	}
+	return nil
 }
 
 // Down function is helper function to clear all databases schema that used by guardian schema
@@ -178,7 +295,7 @@ func (m *Migration) Run(name string, f func(ptx *GuardTx) error) error {
 	}(err)
 
 	// init migration schema
-	migrationSchema := &schema.MigrationSchema{schema.Entity{DBContract: gtx.GetTx()}}
+	migrationSchema := &schema.MigrationSchema{Entity: schema.Entity{DBContract: gtx.GetTx()}}
 
 	// check existing migration
 	alreadyRun, err := migrationSchema.CheckExistingMigration(name)
@@ -202,10 +319,68 @@ func (m *Migration) Run(name string, f func(ptx *GuardTx) error) error {
 	return err
 }
 
-// validateIndexes will check all required indexes in the database
-// It will select all indexes from the database and compare it with requiredIndexes variable.
-// If the value of requiredIndexes with index_name is false, then it'll return error invalid index Schema.
-func (m *Migration) validateIndexes() error {
+// migrateColumns is helper function to add the required columns that don't exist in the existing tables,
+// and widen the required columns that shorter than the current version.
+// the tables that created by the current version already have all columns, so nothing is changed
+func (m *Migration) migrateColumns() error {
+	existing, err := m.existingColumns()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, column := range requiredColumns {
+		var query string
+		length, ok := existing[column.table+"."+column.column]
+		switch {
+		case !ok:
+			query = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.table, column.column, column.definition)
+		case column.length > 0 && length < column.length:
+			query = fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", column.table, column.column, column.definition)
+		default:
+			continue
+		}
+		_, err = m.gSchema.DbConnection.ExecContext(ctx, query)
+		if err != nil {
+			return errors.New(fmt.Sprintf(ErrMigration, fmt.Sprintf("error while migrating column %s.%s, %s", column.table, column.column, err)))
+		}
+	}
+	return nil
+}
+
+// existingColumns will return all columns in the database schema as `table.column` with the character length
+// the length of non character column is 0
+func (m *Migration) existingColumns() (map[string]int64, error) {
+	querySchema := `SELECT 
+		TABLE_NAME AS table_name, 
+		COLUMN_NAME AS column_name, 
+		CHARACTER_MAXIMUM_LENGTH AS column_length 
+	FROM INFORMATION_SCHEMA.COLUMNS 
+	WHERE TABLE_SCHEMA = ?`
+
+	rows, err := m.gSchema.DbConnection.Query(querySchema, m.schemaName)
+	if err != nil {
+		log.Println(err)
+		return nil, errors.New(fmt.Sprintf(ErrMigration, "error while checking the columns"))
+	}
+	defer rows.Close()
+
+	columns := make(map[string]int64)
+	for rows.Next() {
+		var table, column string
+		var length sql.NullInt64
+		err = rows.Scan(&table, &column, &length)
+		if err != nil {
+			log.Println(err)
+			return nil, errors.New(fmt.Sprintf(ErrMigration, "error while checking the columns"))
+		}
+		columns[table+"."+column] = length.Int64
+	}
+	return columns, rows.Err()
+}
+
+// existingIndexes will return the name of all indexes in the database schema except primary key
+func (m *Migration) existingIndexes() (map[string]bool, error) {
 	querySchema := `SELECT DISTINCT 
 		INDEX_NAME AS index_name 
 	FROM INFORMATION_SCHEMA.STATISTICS 
@@ -215,24 +390,35 @@ func (m *Migration) validateIndexes() error {
 	rows, err := m.gSchema.DbConnection.Query(querySchema, m.schemaName, "PRIMARY")
 	if err != nil {
 		log.Println(err)
-		return errors.New(fmt.Sprintf(ErrMigration, "error while checking the tables"))
+		return nil, errors.New(fmt.Sprintf(ErrMigration, "error while checking the tables"))
 	}
+	defer rows.Close()
 
+	indexes := make(map[string]bool)
 	var index indexSchema
 	for rows.Next() {
 		err = rows.Scan(&index.IndexName)
 		if err != nil {
 			log.Println(err)
-			return errors.New(fmt.Sprintf(ErrMigration, "error while checking the indexes"))
+			return nil, errors.New(fmt.Sprintf(ErrMigration, "error while checking the indexes"))
 		}
+		indexes[index.IndexName] = true
+	}
+	return indexes, rows.Err()
+}
 
-		if _, ok := requiredIndexes[index.IndexName]; ok {
-			requiredIndexes[index.IndexName] = true
-		}
+// validateIndexes will check all required indexes in the database
+// It will select all indexes from the database and compare it with requiredIndexes variable.
+// If the index of requiredIndexes doesn't exist, then it'll return error invalid index Schema.
+func (m *Migration) validateIndexes() error {
+	existing, err := m.existingIndexes()
+	if err != nil {
+		return err
 	}
 
-	for _, v := range requiredIndexes {
-		if !v {
+	for name := range requiredIndexes {
+		requiredIndexes[name] = existing[name]
+		if !existing[name] {
 			return errors.New("invalid RBAC index Schema")
 		}
 	}
@@ -1,8 +1,21 @@
+DROP TABLE IF EXISTS guard_resource_acl;
 DROP TABLE IF EXISTS guard_user_group;
+DROP TABLE IF EXISTS guard_group_role;
+DROP TABLE IF EXISTS guard_group_child;
 DROP TABLE IF EXISTS guard_user_role;
 DROP TABLE IF EXISTS guard_role_permission;
+DROP TABLE IF EXISTS guard_role_child;
+DROP TABLE IF EXISTS guard_api_key_permission;
+DROP TABLE IF EXISTS guard_api_key;
+DROP TABLE IF EXISTS guard_audit_log;
+DROP TABLE IF EXISTS guard_password_history;
+DROP TABLE IF EXISTS guard_user_identity;
+DROP TABLE IF EXISTS guard_user_mfa;
+DROP TABLE IF EXISTS guard_user_recovery_code;
 DROP TABLE IF EXISTS guard_user;
 DROP TABLE IF EXISTS guard_permission;
 DROP TABLE IF EXISTS guard_role;
+DROP TABLE IF EXISTS guard_group;
 DROP TABLE IF EXISTS guard_rule;
+DROP TABLE IF EXISTS guard_session;
 DROP TABLE IF EXISTS guard_migration;
\ No newline at end of file
@@ -3,8 +3,10 @@ CREATE TABLE IF NOT EXISTS guard_user (
 	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
 	username VARCHAR(100) NOT NULL,
 	email VARCHAR(100) NOT NULL,
-	password VARCHAR(100) NOT NULL,
+	password VARCHAR(255) NOT NULL,
 	active TINYINT NOT NULL DEFAULT 1,
+	email_verified_at TIMESTAMP NULL DEFAULT NULL,
+	password_changed_at TIMESTAMP NULL DEFAULT NULL,
 
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
@@ -31,18 +33,147 @@ CREATE TABLE IF NOT EXISTS guard_role_permission (
 	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
 	role_id INT UNSIGNED NOT NULL,
 	permission_id INT UNSIGNED NOT NULL,
+	effect VARCHAR(8) NOT NULL DEFAULT 'allow',
 
 	FOREIGN KEY (role_id) REFERENCES guard_role(id) ON DELETE CASCADE,
 	FOREIGN KEY (permission_id) REFERENCES guard_permission(id) ON DELETE CASCADE
 );
+CREATE TABLE IF NOT EXISTS guard_role_child (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	parent_id INT UNSIGNED NOT NULL,
+	child_id INT UNSIGNED NOT NULL,
+
+	FOREIGN KEY (parent_id) REFERENCES guard_role(id) ON DELETE CASCADE,
+	FOREIGN KEY (child_id) REFERENCES guard_role(id) ON DELETE CASCADE
+);
 CREATE TABLE IF NOT EXISTS guard_user_role (
 	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
 	role_id INT UNSIGNED NOT NULL,
 	user_id INT UNSIGNED NOT NULL,
+	domain VARCHAR(64) NOT NULL DEFAULT '',
+	valid_from TIMESTAMP NULL DEFAULT NULL,
+	valid_until TIMESTAMP NULL DEFAULT NULL,
 
 	FOREIGN KEY (role_id) REFERENCES guard_role(id) ON DELETE CASCADE,
 	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
 );
+CREATE TABLE IF NOT EXISTS guard_group (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	name VARCHAR(40) NOT NULL,
+	description TEXT,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS guard_group_child (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	parent_id INT UNSIGNED NOT NULL,
+	child_id INT UNSIGNED NOT NULL,
+
+	FOREIGN KEY (parent_id) REFERENCES guard_group(id) ON DELETE CASCADE,
+	FOREIGN KEY (child_id) REFERENCES guard_group(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_group_role (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	group_id INT UNSIGNED NOT NULL,
+	role_id INT UNSIGNED NOT NULL,
+
+	FOREIGN KEY (group_id) REFERENCES guard_group(id) ON DELETE CASCADE,
+	FOREIGN KEY (role_id) REFERENCES guard_role(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_user_group (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	group_id INT UNSIGNED NOT NULL,
+	user_id INT UNSIGNED NOT NULL,
+
+	FOREIGN KEY (group_id) REFERENCES guard_group(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_resource_acl (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	resource_type VARCHAR(64) NOT NULL,
+	resource_id VARCHAR(64) NOT NULL,
+	principal_type VARCHAR(8) NOT NULL,
+	principal_id INT UNSIGNED NOT NULL,
+	action VARCHAR(40) NOT NULL,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS guard_user_mfa (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INT UNSIGNED NOT NULL,
+	secret VARCHAR(64) NOT NULL,
+	confirmed TINYINT NOT NULL DEFAULT 0,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_user_recovery_code (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INT UNSIGNED NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	used_at TIMESTAMP NULL DEFAULT NULL,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_api_key (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INT UNSIGNED NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	prefix VARCHAR(20) NOT NULL,
+	key_hash CHAR(64) NOT NULL,
+	last_used_at TIMESTAMP NULL DEFAULT NULL,
+	expired_at TIMESTAMP NULL DEFAULT NULL,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_api_key_permission (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	api_key_id INT UNSIGNED NOT NULL,
+	permission_id INT UNSIGNED NOT NULL,
+
+	FOREIGN KEY (api_key_id) REFERENCES guard_api_key(id) ON DELETE CASCADE,
+	FOREIGN KEY (permission_id) REFERENCES guard_permission(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_user_identity (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INT UNSIGNED NOT NULL,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100) NOT NULL DEFAULT '',
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_password_history (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INT UNSIGNED NOT NULL,
+	password VARCHAR(255) NOT NULL,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES guard_user(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS guard_audit_log (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	actor_id INT UNSIGNED NOT NULL,
+	user_id INT UNSIGNED NOT NULL,
+	action VARCHAR(50) NOT NULL,
+	session_id VARCHAR(50) NOT NULL DEFAULT '',
+	ip_address VARCHAR(45) NOT NULL DEFAULT '',
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
 CREATE TABLE IF NOT EXISTS guard_rule (
     id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
     rule_type TINYINT(1) NOT NULL,
@@ -52,6 +183,15 @@ CREATE TABLE IF NOT EXISTS guard_rule (
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
+CREATE TABLE IF NOT EXISTS guard_session (
+	id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	session_key VARCHAR(255) NOT NULL,
+	value TEXT NOT NULL,
+	expired_at TIMESTAMP NULL DEFAULT NULL,
+
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
 CREATE TABLE IF NOT EXISTS guard_migration (
     id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
 	migration_key VARCHAR(100) NOT NULL,
@@ -4,7 +4,27 @@ CREATE UNIQUE INDEX `guard_user_username_idx` ON guard_user(username);
 CREATE UNIQUE INDEX `guard_permission_route_method_idx` ON guard_permission(route, method);
 CREATE UNIQUE INDEX `guard_permission_name_idx` ON guard_permission(name);
 CREATE UNIQUE INDEX `guard_role_name_idx` ON guard_role(name);
-CREATE UNIQUE INDEX `guard_user_role_role_user_idx` on guard_user_role (role_id, user_id);
+CREATE UNIQUE INDEX `guard_user_role_role_user_domain_idx` on guard_user_role (role_id, user_id, domain);
+CREATE INDEX `guard_user_role_valid_until_idx` ON guard_user_role (valid_until);
 CREATE UNIQUE INDEX `guard_role_permission_role_permission_idx` on guard_role_permission (role_id, permission_id);
+CREATE UNIQUE INDEX `guard_role_child_parent_child_idx` on guard_role_child (parent_id, child_id);
+CREATE UNIQUE INDEX `guard_group_name_idx` ON guard_group(name);
+CREATE UNIQUE INDEX `guard_group_child_parent_child_idx` on guard_group_child (parent_id, child_id);
+CREATE UNIQUE INDEX `guard_group_role_group_role_idx` on guard_group_role (group_id, role_id);
+CREATE UNIQUE INDEX `guard_user_group_group_user_idx` on guard_user_group (group_id, user_id);
+CREATE UNIQUE INDEX `guard_resource_acl_entry_idx` ON guard_resource_acl (resource_type, resource_id, principal_type, principal_id, action);
+CREATE INDEX `guard_resource_acl_principal_idx` ON guard_resource_acl (principal_type, principal_id);
+CREATE UNIQUE INDEX `guard_user_mfa_user_idx` ON guard_user_mfa (user_id);
+CREATE INDEX `guard_user_recovery_code_user_code_idx` ON guard_user_recovery_code (user_id, code_hash);
+CREATE UNIQUE INDEX `guard_api_key_prefix_idx` ON guard_api_key (prefix);
+CREATE INDEX `guard_api_key_user_idx` ON guard_api_key (user_id);
+CREATE UNIQUE INDEX `guard_api_key_permission_idx` ON guard_api_key_permission (api_key_id, permission_id);
+CREATE UNIQUE INDEX `guard_user_identity_subject_idx` ON guard_user_identity (provider, subject);
+CREATE INDEX `guard_user_identity_user_idx` ON guard_user_identity (user_id);
+CREATE INDEX `guard_password_history_user_idx` ON guard_password_history (user_id);
+CREATE INDEX `guard_audit_log_actor_idx` ON guard_audit_log (actor_id);
+CREATE INDEX `guard_audit_log_user_idx` ON guard_audit_log (user_id);
 CREATE UNIQUE INDEX `guard_role_guard_rule_idx` ON guard_rule (name, rule_type, parent_id);
 CREATE INDEX `guard_role_guard_rule_checker_idx` ON guard_rule (rule_type, parent_id);
+CREATE UNIQUE INDEX `guard_session_key_idx` ON guard_session (session_key);
+CREATE INDEX `guard_session_expired_at_idx` ON guard_session (expired_at);
\ No newline at end of file
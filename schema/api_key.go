@@ -0,0 +1,429 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	APIKeyNotFound = errors.New("api key is not exist")
+)
+
+// APIKey represents `guard_api_key` table in the database
+// the key is only stored as hash, Prefix is the public part of key that used to find the key
+// the permissions of api key are stored in `guard_api_key_permission` table
+type APIKey struct {
+	Entity
+
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"user_id"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"prefix" json:"prefix"`
+	KeyHash    string     `db:"key_hash" json:"-"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	ExpiredAt  *time.Time `db:"expired_at" json:"expired_at"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	exist bool `json:"-"`
+}
+
+// setDefaultTimeStamp is helper func to set current time for attribute `created_at` and `updated_at`
+func (k *APIKey) setDefaultTimeStamp() {
+	now := time.Now()
+	k.UpdatedAt = now
+	if !k.exist {
+		k.CreatedAt = now
+	}
+}
+
+// IsExpired will check the api key is expired or not
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiredAt != nil && time.Now().After(*k.ExpiredAt)
+}
+
+const insertAPIKeyQuery = `
+	INSERT INTO guard_api_key (
+		user_id,
+		name,
+		prefix,
+		key_hash,
+		expired_at,
+		created_at,
+		updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+// CreateAPIKey function will create a new record of api key entity
+func (k *APIKey) CreateAPIKey() error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	k.setDefaultTimeStamp()
+	result, err := k.DBContract.Exec(
+		insertAPIKeyQuery,
+		k.UserID,
+		k.Name,
+		k.Prefix,
+		k.KeyHash,
+		k.ExpiredAt,
+		k.CreatedAt,
+		k.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	k.ID, err = result.LastInsertId()
+	k.exist = true
+	return err
+}
+
+// CreateAPIKeyContext function will create a new record of api key entity with specific context
+func (k *APIKey) CreateAPIKeyContext(ctx context.Context) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	k.setDefaultTimeStamp()
+	result, err := k.DBContract.ExecContext(
+		ctx,
+		insertAPIKeyQuery,
+		k.UserID,
+		k.Name,
+		k.Prefix,
+		k.KeyHash,
+		k.ExpiredAt,
+		k.CreatedAt,
+		k.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	k.ID, err = result.LastInsertId()
+	k.exist = true
+	return err
+}
+
+const deleteAPIKeyQuery = `DELETE FROM guard_api_key WHERE id = ?`
+
+// Delete function will delete api key entity, the api key can't be used anymore
+func (k *APIKey) Delete() error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if !k.exist {
+		return APIKeyNotFound
+	}
+	if k.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.Exec(deleteAPIKeyQuery, k.ID)
+	if err != nil {
+		return err
+	}
+	k.exist = false
+	return nil
+}
+
+// DeleteContext function will delete api key entity with specific context, the api key can't be used anymore
+func (k *APIKey) DeleteContext(ctx context.Context) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if !k.exist {
+		return APIKeyNotFound
+	}
+	if k.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.ExecContext(ctx, deleteAPIKeyQuery, k.ID)
+	if err != nil {
+		return err
+	}
+	k.exist = false
+	return nil
+}
+
+const updateAPIKeyLastUsedQuery = `UPDATE guard_api_key SET last_used_at = ? WHERE id = ?`
+
+// UpdateLastUsed function will set the last used time of api key
+func (k *APIKey) UpdateLastUsed(lastUsedAt time.Time) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.Exec(updateAPIKeyLastUsedQuery, lastUsedAt, k.ID)
+	if err != nil {
+		return err
+	}
+	k.LastUsedAt = &lastUsedAt
+	return nil
+}
+
+// UpdateLastUsedContext function will set the last used time of api key with specific context
+func (k *APIKey) UpdateLastUsedContext(ctx context.Context, lastUsedAt time.Time) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.ExecContext(ctx, updateAPIKeyLastUsedQuery, lastUsedAt, k.ID)
+	if err != nil {
+		return err
+	}
+	k.LastUsedAt = &lastUsedAt
+	return nil
+}
+
+const addAPIKeyPermissionQuery = `
+	INSERT INTO guard_api_key_permission (
+		api_key_id,
+		permission_id
+	) VALUES (?, ?)
+`
+
+// AddPermission function will add the permission to the scope of api key
+func (k *APIKey) AddPermission(p *Permission) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.ID <= 0 || p.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.Exec(addAPIKeyPermissionQuery, k.ID, p.ID)
+	return err
+}
+
+// AddPermissionContext function will add the permission to the scope of api key with specific context
+func (k *APIKey) AddPermissionContext(ctx context.Context, p *Permission) error {
+	if k.DBContract == nil {
+		return ErrNoSchema
+	}
+	if k.ID <= 0 || p.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := k.DBContract.ExecContext(ctx, addAPIKeyPermissionQuery, k.ID, p.ID)
+	return err
+}
+
+const getAPIKeyPermissionsQuery = `
+	SELECT
+		p.id,
+		p.name,
+		p.method,
+		p.route,
+		p.description,
+		p.created_at,
+		p.updated_at
+	FROM guard_permission p
+	JOIN guard_api_key_permission kp ON kp.permission_id = p.id
+	WHERE kp.api_key_id = ?
+`
+
+// GetPermissions function will return the permissions in the scope of api key
+func (k *APIKey) GetPermissions() ([]Permission, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	result, err := k.DBContract.Query(getAPIKeyPermissionsQuery, k.ID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPermissions(result, k.DBContract)
+}
+
+// GetPermissionsContext function will return the permissions in the scope of api key with specific context
+func (k *APIKey) GetPermissionsContext(ctx context.Context) ([]Permission, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	result, err := k.DBContract.QueryContext(ctx, getAPIKeyPermissionsQuery, k.ID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPermissions(result, k.DBContract)
+}
+
+const checkAPIKeyPermissionQuery = `
+	SELECT EXISTS(
+		SELECT 1 FROM guard_api_key_permission WHERE api_key_id = ? AND permission_id = ?
+	) AS is_exist
+`
+
+// HasPermission function will check the permission is in the scope of api key or not
+func (k *APIKey) HasPermission(p *Permission) (bool, error) {
+	if k.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	var record existRecord
+	err := k.DBContract.QueryRow(checkAPIKeyPermissionQuery, k.ID, p.ID).Scan(&record.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return record.IsExist, nil
+}
+
+// HasPermissionContext function will check the permission is in the scope of api key or not with specific context
+func (k *APIKey) HasPermissionContext(ctx context.Context, p *Permission) (bool, error) {
+	if k.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	var record existRecord
+	err := k.DBContract.QueryRowContext(ctx, checkAPIKeyPermissionQuery, k.ID, p.ID).Scan(&record.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return record.IsExist, nil
+}
+
+const fetchAPIKeyQuery = `
+	SELECT
+		id,
+		user_id,
+		name,
+		prefix,
+		key_hash,
+		last_used_at,
+		expired_at,
+		created_at,
+		updated_at
+	FROM guard_api_key
+`
+
+// scanAPIKeys is helper func to scan api key rows
+func scanAPIKeys(rows *sql.Rows, dbContract DbContract) ([]APIKey, error) {
+	defer rows.Close()
+
+	apiKeys := make([]APIKey, 0)
+	for rows.Next() {
+		var apiKey APIKey
+		err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.UserID,
+			&apiKey.Name,
+			&apiKey.Prefix,
+			&apiKey.KeyHash,
+			&apiKey.LastUsedAt,
+			&apiKey.ExpiredAt,
+			&apiKey.CreatedAt,
+			&apiKey.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.DBContract = dbContract
+		apiKey.exist = true
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, rows.Err()
+}
+
+// findAPIKey is helper func to get the first api key from the rows
+func findAPIKey(rows *sql.Rows, err error, dbContract DbContract) (*APIKey, error) {
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := scanAPIKeys(rows, dbContract)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) == 0 {
+		return nil, nil
+	}
+	return &apiKeys[0], nil
+}
+
+// FindAPIKey function will return api key by id
+// nil will be returned if api key doesn't exist
+func (k *APIKey) FindAPIKey(id int64) (*APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.Query(fetchAPIKeyQuery+` WHERE id = ? LIMIT 1`, id)
+	return findAPIKey(rows, err, k.DBContract)
+}
+
+// FindAPIKeyContext function will return api key by id with specific context
+// nil will be returned if api key doesn't exist
+func (k *APIKey) FindAPIKeyContext(ctx context.Context, id int64) (*APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.QueryContext(ctx, fetchAPIKeyQuery+` WHERE id = ? LIMIT 1`, id)
+	return findAPIKey(rows, err, k.DBContract)
+}
+
+// FindAPIKeyByPrefix function will return api key by the public prefix
+// nil will be returned if api key doesn't exist
+func (k *APIKey) FindAPIKeyByPrefix(prefix string) (*APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.Query(fetchAPIKeyQuery+` WHERE prefix = ? LIMIT 1`, prefix)
+	return findAPIKey(rows, err, k.DBContract)
+}
+
+// FindAPIKeyByPrefixContext function will return api key by the public prefix with specific context
+// nil will be returned if api key doesn't exist
+func (k *APIKey) FindAPIKeyByPrefixContext(ctx context.Context, prefix string) (*APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.QueryContext(ctx, fetchAPIKeyQuery+` WHERE prefix = ? LIMIT 1`, prefix)
+	return findAPIKey(rows, err, k.DBContract)
+}
+
+// GetUserAPIKeys function will return all api keys of user
+func (k *APIKey) GetUserAPIKeys(userID int64) ([]APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.Query(fetchAPIKeyQuery+` WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAPIKeys(rows, k.DBContract)
+}
+
+// GetUserAPIKeysContext function will return all api keys of user with specific context
+func (k *APIKey) GetUserAPIKeysContext(ctx context.Context, userID int64) ([]APIKey, error) {
+	if k.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := k.DBContract.QueryContext(ctx, fetchAPIKeyQuery+` WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAPIKeys(rows, k.DBContract)
+}
@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AuditLog represents `guard_audit_log` table in the database
+// It records the sensitive action that done by actor to user, e.g. impersonation
+type AuditLog struct {
+	Entity
+
+	ID        int64  `db:"id" json:"id"`
+	ActorID   int64  `db:"actor_id" json:"actor_id"`
+	UserID    int64  `db:"user_id" json:"user_id"`
+	Action    string `db:"action" json:"action"`
+	SessionID string `db:"session_id" json:"session_id"`
+	IPAddress string `db:"ip_address" json:"ip_address"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+const insertAuditLogQuery = `
+	INSERT INTO guard_audit_log (
+		actor_id,
+		user_id,
+		action,
+		session_id,
+		ip_address,
+		created_at
+	) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+// CreateAuditLog function will create a new record of audit log
+func (l *AuditLog) CreateAuditLog() error {
+	if l.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	l.CreatedAt = time.Now()
+	result, err := l.DBContract.Exec(
+		insertAuditLogQuery,
+		l.ActorID,
+		l.UserID,
+		l.Action,
+		l.SessionID,
+		l.IPAddress,
+		l.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	l.ID, err = result.LastInsertId()
+	return err
+}
+
+// CreateAuditLogContext function will create a new record of audit log with specific context
+func (l *AuditLog) CreateAuditLogContext(ctx context.Context) error {
+	if l.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	l.CreatedAt = time.Now()
+	result, err := l.DBContract.ExecContext(
+		ctx,
+		insertAuditLogQuery,
+		l.ActorID,
+		l.UserID,
+		l.Action,
+		l.SessionID,
+		l.IPAddress,
+		l.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	l.ID, err = result.LastInsertId()
+	return err
+}
+
+const fetchAuditLogsQuery = `
+	SELECT
+		id,
+		actor_id,
+		user_id,
+		action,
+		session_id,
+		ip_address,
+		created_at
+	FROM guard_audit_log
+	WHERE actor_id = ? OR user_id = ?
+	ORDER BY id DESC
+	LIMIT ?
+`
+
+// scanAuditLogs is helper func to scan audit log rows
+func scanAuditLogs(rows *sql.Rows) ([]AuditLog, error) {
+	defer rows.Close()
+
+	logs := make([]AuditLog, 0)
+	for rows.Next() {
+		var log AuditLog
+		err := rows.Scan(
+			&log.ID,
+			&log.ActorID,
+			&log.UserID,
+			&log.Action,
+			&log.SessionID,
+			&log.IPAddress,
+			&log.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
+
+// GetAuditLogs function will return the last n audit logs that done by or to user, the newest log is returned first
+func (l *AuditLog) GetAuditLogs(userID int64, n int) ([]AuditLog, error) {
+	if l.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := l.DBContract.Query(fetchAuditLogsQuery, userID, userID, n)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditLogs(rows)
+}
+
+// GetAuditLogsContext function will return the last n audit logs that done by or to user with specific context
+func (l *AuditLog) GetAuditLogsContext(ctx context.Context, userID int64, n int) ([]AuditLog, error) {
+	if l.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := l.DBContract.QueryContext(ctx, fetchAuditLogsQuery, userID, userID, n)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditLogs(rows)
+}
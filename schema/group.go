@@ -0,0 +1,914 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	GroupNotFound = errors.New("group is not exist")
+	ErrGroupCycle = errors.New("group hierarchy cannot contain a cycle")
+)
+
+// Group represents `guard_group` table in the database
+// The members of group inherit all roles assigned to the group and its parent groups
+type Group struct {
+	Entity
+
+	ID          int64  `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	exist     bool            `json:"-"`
+	validator *GroupValidator `json:"-"`
+}
+
+// SetValidator is setter function to set validator in group entity
+func (g *Group) SetValidator(validator *GroupValidator) {
+	g.validator = validator
+}
+
+// Validate will validate all value in group entity
+func (g *Group) validate() error {
+	// validate name
+	return g.validator.Name.validateLen("name", g.Name)
+}
+
+// setDefaultTimeStamp is helper func to set current time for attribute `created_at` and `updated_at`
+func (g *Group) setDefaultTimeStamp() {
+	now := time.Now()
+	g.UpdatedAt = now
+	if !g.exist {
+		g.CreatedAt = now
+	}
+}
+
+const insertGroupQuery = `
+	INSERT INTO guard_group (
+		name,
+		description,
+		created_at,
+		updated_at
+	) VALUES (?, ?, ?, ?)
+`
+
+// CreateGroup function will create a new record of group entity
+func (g *Group) CreateGroup() error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := g.validate()
+	if err != nil {
+		return err
+	}
+
+	g.setDefaultTimeStamp()
+
+	result, err := g.DBContract.Exec(
+		insertGroupQuery,
+		g.Name,
+		g.Description,
+		g.CreatedAt,
+		g.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	g.ID, _ = result.LastInsertId()
+	g.exist = true
+	return nil
+}
+
+// CreateGroupContext function will create a new record of group entity with specific context
+func (g *Group) CreateGroupContext(ctx context.Context) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := g.validate()
+	if err != nil {
+		return err
+	}
+
+	g.setDefaultTimeStamp()
+
+	result, err := g.DBContract.ExecContext(
+		ctx,
+		insertGroupQuery,
+		g.Name,
+		g.Description,
+		g.CreatedAt,
+		g.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	g.ID, _ = result.LastInsertId()
+	g.exist = true
+	return nil
+}
+
+const saveGroupQuery = `
+	INSERT INTO guard_group (
+		name,
+		description,
+		created_at,
+		updated_at
+	) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), description = ?, updated_at = ?
+`
+
+// Save function will save updated group entity
+// if group record with the same name already exist in the database, it will be updated
+// otherwise it will create a new one
+func (g *Group) Save() error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	// validate data
+	err := g.validate()
+	if err != nil {
+		return err
+	}
+
+	g.setDefaultTimeStamp()
+
+	result, err := g.DBContract.Exec(
+		saveGroupQuery,
+		g.Name,
+		g.Description,
+		g.CreatedAt,
+		g.UpdatedAt,
+		g.Description,
+		g.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	g.ID, _ = result.LastInsertId()
+	g.exist = true
+	return nil
+}
+
+// SaveContext function will save updated group entity with specific context
+// if group record with the same name already exist in the database, it will be updated
+// otherwise it will create a new one
+func (g *Group) SaveContext(ctx context.Context) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := g.validate()
+	if err != nil {
+		return err
+	}
+
+	g.setDefaultTimeStamp()
+
+	result, err := g.DBContract.ExecContext(
+		ctx,
+		saveGroupQuery,
+		g.Name,
+		g.Description,
+		g.CreatedAt,
+		g.UpdatedAt,
+		g.Description,
+		g.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	g.ID, _ = result.LastInsertId()
+	g.exist = true
+	return nil
+}
+
+const deleteGroupQuery = `DELETE FROM guard_group WHERE id = ?`
+
+// Delete function will delete group entity with specific ID
+// the memberships, role assignments and child relations of group are deleted too
+func (g *Group) Delete() error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		deleteGroupQuery,
+		g.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteContext function will delete group entity with specific ID and context
+// the memberships, role assignments and child relations of group are deleted too
+func (g *Group) DeleteContext(ctx context.Context) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		deleteGroupQuery,
+		g.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const fetchGroupQuery = `
+	SELECT
+		id,
+		name,
+		description,
+		created_at,
+		updated_at
+	FROM guard_group WHERE name = ?
+`
+
+// GetGroup function will get the group entity by name
+// nil will be returned if group doesn't exist
+func (g *Group) GetGroup(name string) (*Group, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	var group = new(Group)
+	result := g.DBContract.QueryRow(fetchGroupQuery, name)
+	err := result.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	group.DBContract = g.DBContract
+	group.validator = g.validator
+	group.exist = true
+	return group, nil
+}
+
+// GetGroupContext function will get the group entity by name with specific context
+// nil will be returned if group doesn't exist
+func (g *Group) GetGroupContext(ctx context.Context, name string) (*Group, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	var group = new(Group)
+	result := g.DBContract.QueryRowContext(ctx, fetchGroupQuery, name)
+	err := result.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	group.DBContract = g.DBContract
+	group.validator = g.validator
+	group.exist = true
+	return group, nil
+}
+
+const addMemberQuery = `
+	INSERT INTO guard_user_group (
+		group_id,
+		user_id
+	) VALUES (?,?)
+`
+
+// AddMember function will add the user as member of this group
+// the user will inherit all roles of this group and its parent groups
+func (g *Group) AddMember(u *User) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if u == nil || !u.exist {
+		return UserNotFound
+	}
+
+	if g.ID <= 0 || u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		addMemberQuery,
+		g.ID,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddMemberContext function will add the user as member of this group with specific context
+// the user will inherit all roles of this group and its parent groups
+func (g *Group) AddMemberContext(ctx context.Context, u *User) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if u == nil || !u.exist {
+		return UserNotFound
+	}
+
+	if g.ID <= 0 || u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		addMemberQuery,
+		g.ID,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const removeMemberQuery = `DELETE FROM guard_user_group WHERE group_id = ? AND user_id = ?`
+
+// RemoveMember function will remove the user from the members of this group
+func (g *Group) RemoveMember(u *User) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if u == nil || !u.exist {
+		return UserNotFound
+	}
+
+	if g.ID <= 0 || u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		removeMemberQuery,
+		g.ID,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveMemberContext function will remove the user from the members of this group with specific context
+func (g *Group) RemoveMemberContext(ctx context.Context, u *User) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if u == nil || !u.exist {
+		return UserNotFound
+	}
+
+	if g.ID <= 0 || u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		removeMemberQuery,
+		g.ID,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const getMembersQuery = `
+	SELECT
+		u.id,
+		u.email,
+		u.username,
+		u.password,
+		u.active,
+		u.email_verified_at,
+		u.password_changed_at,
+		u.created_at,
+		u.updated_at
+	FROM guard_user u
+	JOIN guard_user_group ug ON ug.user_id = u.id
+	WHERE ug.group_id = ?
+`
+
+// GetMembers function will return the direct members of this group
+// the members of child groups aren't included
+func (g *Group) GetMembers() ([]User, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.Query(getMembersQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]User, 0), nil
+		}
+		return nil, err
+	}
+	return scanUsers(result, g.DBContract)
+}
+
+// GetMembersContext function will return the direct members of this group with specific context
+// the members of child groups aren't included
+func (g *Group) GetMembersContext(ctx context.Context) ([]User, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.QueryContext(ctx, getMembersQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]User, 0), nil
+		}
+		return nil, err
+	}
+	return scanUsers(result, g.DBContract)
+}
+
+const assignGroupRoleQuery = `
+	INSERT INTO guard_group_role (
+		group_id,
+		role_id
+	) VALUES (?,?)
+`
+
+// AssignRole function will assign the role to this group
+// the members of this group and its child groups will inherit the role
+func (g *Group) AssignRole(role *Role) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if role == nil || !role.exist {
+		return RoleNotFound
+	}
+
+	if g.ID <= 0 || role.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		assignGroupRoleQuery,
+		g.ID,
+		role.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AssignRoleContext function will assign the role to this group with specific context
+// the members of this group and its child groups will inherit the role
+func (g *Group) AssignRoleContext(ctx context.Context, role *Role) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if role == nil || !role.exist {
+		return RoleNotFound
+	}
+
+	if g.ID <= 0 || role.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		assignGroupRoleQuery,
+		g.ID,
+		role.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const revokeGroupRoleQuery = `DELETE FROM guard_group_role WHERE group_id = ? AND role_id = ?`
+
+// RevokeRole function will revoke the role from this group
+func (g *Group) RevokeRole(role *Role) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if role == nil || !role.exist {
+		return RoleNotFound
+	}
+
+	if g.ID <= 0 || role.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		revokeGroupRoleQuery,
+		g.ID,
+		role.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeRoleContext function will revoke the role from this group with specific context
+func (g *Group) RevokeRoleContext(ctx context.Context, role *Role) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist {
+		return GroupNotFound
+	}
+
+	if role == nil || !role.exist {
+		return RoleNotFound
+	}
+
+	if g.ID <= 0 || role.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		revokeGroupRoleQuery,
+		g.ID,
+		role.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const getGroupRolesQuery = `
+	SELECT
+		r.id,
+		r.name,
+		r.description,
+		r.created_at,
+		r.updated_at
+	FROM guard_role r
+	JOIN guard_group_role gr ON gr.role_id = r.id
+	WHERE gr.group_id = ?
+`
+
+// GetRoles function will return the roles assigned directly to this group
+// the roles inherited from the parent groups aren't included
+func (g *Group) GetRoles() ([]Role, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.Query(getGroupRolesQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Role, 0), nil
+		}
+		return nil, err
+	}
+	return scanRoles(result, g.DBContract)
+}
+
+// GetRolesContext function will return the roles assigned directly to this group with specific context
+// the roles inherited from the parent groups aren't included
+func (g *Group) GetRolesContext(ctx context.Context) ([]Role, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.QueryContext(ctx, getGroupRolesQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Role, 0), nil
+		}
+		return nil, err
+	}
+	return scanRoles(result, g.DBContract)
+}
+
+const checkGroupDescendantQuery = `
+	WITH RECURSIVE descendants (group_id) AS (
+		SELECT child_id FROM guard_group_child WHERE parent_id = ?
+		UNION
+		SELECT gc.child_id FROM guard_group_child gc
+		JOIN descendants d ON gc.parent_id = d.group_id
+	)
+	SELECT EXISTS(
+		SELECT * FROM descendants WHERE group_id = ?
+	) AS is_exist
+`
+
+const addGroupChildQuery = `
+	INSERT INTO guard_group_child (
+		parent_id,
+		child_id
+	) VALUES (?,?)
+`
+
+// AddChild function will add the child group to this group
+// The members of child group and its descendants will inherit all roles of this group
+// ErrGroupCycle will be returned if this group is the child group itself or one of its descendants
+func (g *Group) AddChild(child *Group) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist || child == nil || !child.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	if g.ID == child.ID {
+		return ErrGroupCycle
+	}
+
+	var descendantRecord existRecord
+	result := g.DBContract.QueryRow(checkGroupDescendantQuery, child.ID, g.ID)
+	err := result.Scan(&descendantRecord.IsExist)
+	if err != nil {
+		return err
+	}
+	if descendantRecord.IsExist {
+		return ErrGroupCycle
+	}
+
+	_, err = g.DBContract.Exec(
+		addGroupChildQuery,
+		g.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddChildContext function will add the child group to this group with specific context
+// The members of child group and its descendants will inherit all roles of this group
+// ErrGroupCycle will be returned if this group is the child group itself or one of its descendants
+func (g *Group) AddChildContext(ctx context.Context, child *Group) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist || child == nil || !child.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	if g.ID == child.ID {
+		return ErrGroupCycle
+	}
+
+	var descendantRecord existRecord
+	result := g.DBContract.QueryRowContext(ctx, checkGroupDescendantQuery, child.ID, g.ID)
+	err := result.Scan(&descendantRecord.IsExist)
+	if err != nil {
+		return err
+	}
+	if descendantRecord.IsExist {
+		return ErrGroupCycle
+	}
+
+	_, err = g.DBContract.ExecContext(
+		ctx,
+		addGroupChildQuery,
+		g.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const removeGroupChildQuery = `DELETE FROM guard_group_child WHERE parent_id = ? AND child_id = ?`
+
+// RemoveChild function will remove the child group from this group
+func (g *Group) RemoveChild(child *Group) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist || child == nil || !child.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.Exec(
+		removeGroupChildQuery,
+		g.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveChildContext function will remove the child group from this group with specific context
+func (g *Group) RemoveChildContext(ctx context.Context, child *Group) error {
+	if g.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !g.exist || child == nil || !child.exist {
+		return GroupNotFound
+	}
+
+	if g.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := g.DBContract.ExecContext(
+		ctx,
+		removeGroupChildQuery,
+		g.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+const getGroupChildrenQuery = `
+	SELECT
+		g.id,
+		g.name,
+		g.description,
+		g.created_at,
+		g.updated_at
+	FROM guard_group g
+	JOIN guard_group_child gc ON gc.child_id = g.id
+	WHERE gc.parent_id = ?
+`
+
+// GetChildren function will return the direct child groups of this group
+func (g *Group) GetChildren() ([]Group, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.Query(getGroupChildrenQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Group, 0), nil
+		}
+		return nil, err
+	}
+	return scanGroups(result, g.DBContract)
+}
+
+// GetChildrenContext function will return the direct child groups of this group with specific context
+func (g *Group) GetChildrenContext(ctx context.Context) ([]Group, error) {
+	if g.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !g.exist {
+		return nil, GroupNotFound
+	}
+
+	result, err := g.DBContract.QueryContext(ctx, getGroupChildrenQuery, g.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Group, 0), nil
+		}
+		return nil, err
+	}
+	return scanGroups(result, g.DBContract)
+}
+
+// scanGroups is helper func to scan group rows into group collection
+func scanGroups(rows *sql.Rows, dbContract DbContract) ([]Group, error) {
+	defer rows.Close()
+
+	groups := make([]Group, 0)
+	var group Group
+	group.DBContract = dbContract
+	for rows.Next() {
+		err := rows.Scan(
+			&group.ID,
+			&group.Name,
+			&group.Description,
+			&group.CreatedAt,
+			&group.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		group.exist = true
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,248 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	IdentityNotFound = errors.New("user identity is not exist")
+)
+
+// UserIdentity represents `guard_user_identity` table in the database
+// It links the external identity from identity provider, e.g. OpenID Connect provider, to user
+type UserIdentity struct {
+	Entity
+
+	ID       int64  `db:"id" json:"id"`
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Provider string `db:"provider" json:"provider"`
+	Subject  string `db:"subject" json:"subject"`
+	Email    string `db:"email" json:"email"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	exist bool `json:"-"`
+}
+
+// setDefaultTimeStamp is helper func to set current time for attribute `created_at` and `updated_at`
+func (i *UserIdentity) setDefaultTimeStamp() {
+	now := time.Now()
+	i.UpdatedAt = now
+	if !i.exist {
+		i.CreatedAt = now
+	}
+}
+
+const insertIdentityQuery = `
+	INSERT INTO guard_user_identity (
+		user_id,
+		provider,
+		subject,
+		email,
+		created_at,
+		updated_at
+	) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+// CreateIdentity function will create a new record of user identity entity
+func (i *UserIdentity) CreateIdentity() error {
+	if i.DBContract == nil {
+		return ErrNoSchema
+	}
+	if i.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	i.setDefaultTimeStamp()
+	result, err := i.DBContract.Exec(
+		insertIdentityQuery,
+		i.UserID,
+		i.Provider,
+		i.Subject,
+		i.Email,
+		i.CreatedAt,
+		i.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	i.ID, err = result.LastInsertId()
+	i.exist = true
+	return err
+}
+
+// CreateIdentityContext function will create a new record of user identity entity with specific context
+func (i *UserIdentity) CreateIdentityContext(ctx context.Context) error {
+	if i.DBContract == nil {
+		return ErrNoSchema
+	}
+	if i.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	i.setDefaultTimeStamp()
+	result, err := i.DBContract.ExecContext(
+		ctx,
+		insertIdentityQuery,
+		i.UserID,
+		i.Provider,
+		i.Subject,
+		i.Email,
+		i.CreatedAt,
+		i.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	i.ID, err = result.LastInsertId()
+	i.exist = true
+	return err
+}
+
+const deleteIdentityQuery = `DELETE FROM guard_user_identity WHERE id = ?`
+
+// Delete function will unlink the identity from user
+func (i *UserIdentity) Delete() error {
+	if i.DBContract == nil {
+		return ErrNoSchema
+	}
+	if !i.exist {
+		return IdentityNotFound
+	}
+	if i.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := i.DBContract.Exec(deleteIdentityQuery, i.ID)
+	if err != nil {
+		return err
+	}
+	i.exist = false
+	return nil
+}
+
+// DeleteContext function will unlink the identity from user with specific context
+func (i *UserIdentity) DeleteContext(ctx context.Context) error {
+	if i.DBContract == nil {
+		return ErrNoSchema
+	}
+	if !i.exist {
+		return IdentityNotFound
+	}
+	if i.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := i.DBContract.ExecContext(ctx, deleteIdentityQuery, i.ID)
+	if err != nil {
+		return err
+	}
+	i.exist = false
+	return nil
+}
+
+const fetchIdentityQuery = `
+	SELECT
+		id,
+		user_id,
+		provider,
+		subject,
+		email,
+		created_at,
+		updated_at
+	FROM guard_user_identity
+`
+
+// scanIdentities is helper func to scan user identity rows
+func scanIdentities(rows *sql.Rows, dbContract DbContract) ([]UserIdentity, error) {
+	defer rows.Close()
+
+	identities := make([]UserIdentity, 0)
+	for rows.Next() {
+		var identity UserIdentity
+		err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identity.DBContract = dbContract
+		identity.exist = true
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// FindIdentity function will return the user identity by provider and subject
+// nil will be returned if the identity isn't linked to any user
+func (i *UserIdentity) FindIdentity(provider, subject string) (*UserIdentity, error) {
+	if i.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := i.DBContract.Query(fetchIdentityQuery+` WHERE provider = ? AND subject = ? LIMIT 1`, provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := scanIdentities(rows, i.DBContract)
+	if err != nil || len(identities) == 0 {
+		return nil, err
+	}
+	return &identities[0], nil
+}
+
+// FindIdentityContext function will return the user identity by provider and subject with specific context
+// nil will be returned if the identity isn't linked to any user
+func (i *UserIdentity) FindIdentityContext(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	if i.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := i.DBContract.QueryContext(ctx, fetchIdentityQuery+` WHERE provider = ? AND subject = ? LIMIT 1`, provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := scanIdentities(rows, i.DBContract)
+	if err != nil || len(identities) == 0 {
+		return nil, err
+	}
+	return &identities[0], nil
+}
+
+// GetUserIdentities function will return all identities that linked to user
+func (i *UserIdentity) GetUserIdentities(userID int64) ([]UserIdentity, error) {
+	if i.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := i.DBContract.Query(fetchIdentityQuery+` WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanIdentities(rows, i.DBContract)
+}
+
+// GetUserIdentitiesContext function will return all identities that linked to user with specific context
+func (i *UserIdentity) GetUserIdentitiesContext(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	if i.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := i.DBContract.QueryContext(ctx, fetchIdentityQuery+` WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanIdentities(rows, i.DBContract)
+}
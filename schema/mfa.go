@@ -0,0 +1,398 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	UserMFANotFound = errors.New("user mfa is not exist")
+)
+
+// UserMFA represents `guard_user_mfa` table in the database
+// the recovery codes of user are stored as hash in `guard_user_recovery_code` table
+type UserMFA struct {
+	Entity
+
+	ID           int64  `db:"id" json:"id"`
+	UserID       int64  `db:"user_id" json:"user_id"`
+	Secret       string `db:"secret" json:"-"`
+	Confirmed    bool   `db:"confirmed" json:"confirmed"`
+	LastUsedStep int64  `db:"last_used_step" json:"-"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	exist bool `json:"-"`
+}
+
+// setDefaultTimeStamp is helper func to set current time for attribute `created_at` and `updated_at`
+func (m *UserMFA) setDefaultTimeStamp() {
+	now := time.Now()
+	m.UpdatedAt = now
+	if !m.exist {
+		m.CreatedAt = now
+	}
+}
+
+const saveUserMFAQuery = `
+	INSERT INTO guard_user_mfa (
+		user_id,
+		secret,
+		confirmed,
+		last_used_step,
+		created_at,
+		updated_at
+	) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE secret = ?, confirmed = ?, last_used_step = ?, updated_at = ?
+`
+
+// Save function will save user mfa entity
+// if user already has mfa record, it will be updated, otherwise it will create a new one
+func (m *UserMFA) Save() error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	m.setDefaultTimeStamp()
+	result, err := m.DBContract.Exec(
+		saveUserMFAQuery,
+		m.UserID,
+		m.Secret,
+		m.Confirmed,
+		m.LastUsedStep,
+		m.CreatedAt,
+		m.UpdatedAt,
+		m.Secret,
+		m.Confirmed,
+		m.LastUsedStep,
+		m.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !m.exist {
+		m.ID, _ = result.LastInsertId()
+	}
+	m.exist = true
+	return nil
+}
+
+// SaveContext function will save user mfa entity with specific context
+// if user already has mfa record, it will be updated, otherwise it will create a new one
+func (m *UserMFA) SaveContext(ctx context.Context) error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	m.setDefaultTimeStamp()
+	result, err := m.DBContract.ExecContext(
+		ctx,
+		saveUserMFAQuery,
+		m.UserID,
+		m.Secret,
+		m.Confirmed,
+		m.LastUsedStep,
+		m.CreatedAt,
+		m.UpdatedAt,
+		m.Secret,
+		m.Confirmed,
+		m.LastUsedStep,
+		m.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !m.exist {
+		m.ID, _ = result.LastInsertId()
+	}
+	m.exist = true
+	return nil
+}
+
+const deleteUserMFAQuery = `DELETE FROM guard_user_mfa WHERE user_id = ?`
+const deleteRecoveryCodesQuery = `DELETE FROM guard_user_recovery_code WHERE user_id = ?`
+
+// Delete function will delete user mfa entity and all recovery codes of user
+func (m *UserMFA) Delete() error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := m.DBContract.Exec(deleteRecoveryCodesQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = m.DBContract.Exec(deleteUserMFAQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	m.exist = false
+	return nil
+}
+
+// DeleteContext function will delete user mfa entity and all recovery codes of user with specific context
+func (m *UserMFA) DeleteContext(ctx context.Context) error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := m.DBContract.ExecContext(ctx, deleteRecoveryCodesQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = m.DBContract.ExecContext(ctx, deleteUserMFAQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	m.exist = false
+	return nil
+}
+
+const useTimeStepQuery = `
+	UPDATE guard_user_mfa SET last_used_step = ?, updated_at = ?
+	WHERE user_id = ? AND last_used_step < ?
+`
+
+// UseTimeStep function will mark the TOTP time step as used
+// It'll return false if the time step or the later one has been used, so the same code can't be used twice
+func (m *UserMFA) UseTimeStep(step int64) (bool, error) {
+	if m.DBContract == nil {
+		return false, ErrNoSchema
+	}
+	if !m.exist {
+		return false, UserMFANotFound
+	}
+
+	now := time.Now()
+	result, err := m.DBContract.Exec(useTimeStepQuery, step, now, m.UserID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	m.LastUsedStep = step
+	m.UpdatedAt = now
+	return true, nil
+}
+
+// UseTimeStepContext function will mark the TOTP time step as used with specific context
+// It'll return false if the time step or the later one has been used, so the same code can't be used twice
+func (m *UserMFA) UseTimeStepContext(ctx context.Context, step int64) (bool, error) {
+	if m.DBContract == nil {
+		return false, ErrNoSchema
+	}
+	if !m.exist {
+		return false, UserMFANotFound
+	}
+
+	now := time.Now()
+	result, err := m.DBContract.ExecContext(ctx, useTimeStepQuery, step, now, m.UserID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	m.LastUsedStep = step
+	m.UpdatedAt = now
+	return true, nil
+}
+
+const insertRecoveryCodeQuery = `
+	INSERT INTO guard_user_recovery_code (
+		user_id,
+		code_hash,
+		created_at
+	) VALUES
+`
+
+// buildRecoveryCodesQuery is helper func to build bulk insert query of recovery codes
+func (m *UserMFA) buildRecoveryCodesQuery(hashes []string) (string, []interface{}) {
+	now := time.Now()
+	placeholders := make([]string, 0, len(hashes))
+	args := make([]interface{}, 0, len(hashes)*3)
+	for _, hash := range hashes {
+		placeholders = append(placeholders, "(?, ?, ?)")
+		args = append(args, m.UserID, hash, now)
+	}
+	return insertRecoveryCodeQuery + strings.Join(placeholders, ", "), args
+}
+
+// SetRecoveryCodes function will replace all recovery codes of user with the hashed codes
+func (m *UserMFA) SetRecoveryCodes(hashes []string) error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := m.DBContract.Exec(deleteRecoveryCodesQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	query, args := m.buildRecoveryCodesQuery(hashes)
+	_, err = m.DBContract.Exec(query, args...)
+	return err
+}
+
+// SetRecoveryCodesContext function will replace all recovery codes of user with the hashed codes with specific context
+func (m *UserMFA) SetRecoveryCodesContext(ctx context.Context, hashes []string) error {
+	if m.DBContract == nil {
+		return ErrNoSchema
+	}
+	if m.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	_, err := m.DBContract.ExecContext(ctx, deleteRecoveryCodesQuery, m.UserID)
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	query, args := m.buildRecoveryCodesQuery(hashes)
+	_, err = m.DBContract.ExecContext(ctx, query, args...)
+	return err
+}
+
+const useRecoveryCodeQuery = `
+	UPDATE guard_user_recovery_code SET used_at = ?
+	WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+`
+
+// UseRecoveryCode function will mark the hashed recovery code as used
+// It'll return false if the recovery code doesn't exist or has been used
+func (m *UserMFA) UseRecoveryCode(hash string) (bool, error) {
+	if m.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	result, err := m.DBContract.Exec(useRecoveryCodeQuery, time.Now(), m.UserID, hash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UseRecoveryCodeContext function will mark the hashed recovery code as used with specific context
+// It'll return false if the recovery code doesn't exist or has been used
+func (m *UserMFA) UseRecoveryCodeContext(ctx context.Context, hash string) (bool, error) {
+	if m.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	result, err := m.DBContract.ExecContext(ctx, useRecoveryCodeQuery, time.Now(), m.UserID, hash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+const fetchUserMFAQuery = `
+	SELECT
+		id,
+		user_id,
+		secret,
+		confirmed,
+		last_used_step,
+		created_at,
+		updated_at
+	FROM guard_user_mfa WHERE user_id = ? LIMIT 1
+`
+
+// FindUserMFA function will return the mfa record of user
+// nil will be returned if user hasn't enrolled mfa
+func (m *UserMFA) FindUserMFA(userID int64) (*UserMFA, error) {
+	if m.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	userMFA := new(UserMFA)
+	err := m.DBContract.QueryRow(fetchUserMFAQuery, userID).Scan(
+		&userMFA.ID,
+		&userMFA.UserID,
+		&userMFA.Secret,
+		&userMFA.Confirmed,
+		&userMFA.LastUsedStep,
+		&userMFA.CreatedAt,
+		&userMFA.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	userMFA.DBContract = m.DBContract
+	userMFA.exist = true
+	return userMFA, nil
+}
+
+// FindUserMFAContext function will return the mfa record of user with specific context
+// nil will be returned if user hasn't enrolled mfa
+func (m *UserMFA) FindUserMFAContext(ctx context.Context, userID int64) (*UserMFA, error) {
+	if m.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	userMFA := new(UserMFA)
+	err := m.DBContract.QueryRowContext(ctx, fetchUserMFAQuery, userID).Scan(
+		&userMFA.ID,
+		&userMFA.UserID,
+		&userMFA.Secret,
+		&userMFA.Confirmed,
+		&userMFA.LastUsedStep,
+		&userMFA.CreatedAt,
+		&userMFA.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	userMFA.DBContract = m.DBContract
+	userMFA.exist = true
+	return userMFA, nil
+}
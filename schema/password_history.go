@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordHistory represents `guard_password_history` table in the database
+// It keeps the previous password hashes of user to prevent password reuse
+type PasswordHistory struct {
+	Entity
+
+	ID       int64  `db:"id" json:"id"`
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Password string `db:"password" json:"-"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+const insertPasswordHistoryQuery = `
+	INSERT INTO guard_password_history (
+		user_id,
+		password,
+		created_at
+	) VALUES (?, ?, ?)
+`
+
+// CreatePasswordHistory function will create a new record of password history
+func (h *PasswordHistory) CreatePasswordHistory() error {
+	if h.DBContract == nil {
+		return ErrNoSchema
+	}
+	if h.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	h.CreatedAt = time.Now()
+	result, err := h.DBContract.Exec(
+		insertPasswordHistoryQuery,
+		h.UserID,
+		h.Password,
+		h.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	h.ID, err = result.LastInsertId()
+	return err
+}
+
+// CreatePasswordHistoryContext function will create a new record of password history with specific context
+func (h *PasswordHistory) CreatePasswordHistoryContext(ctx context.Context) error {
+	if h.DBContract == nil {
+		return ErrNoSchema
+	}
+	if h.UserID <= 0 {
+		return ErrInvalidID
+	}
+
+	h.CreatedAt = time.Now()
+	result, err := h.DBContract.ExecContext(
+		ctx,
+		insertPasswordHistoryQuery,
+		h.UserID,
+		h.Password,
+		h.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	h.ID, err = result.LastInsertId()
+	return err
+}
+
+const fetchRecentPasswordsQuery = `
+	SELECT password FROM guard_password_history
+	WHERE user_id = ?
+	ORDER BY id DESC
+	LIMIT ?
+`
+
+// GetRecentPasswords function will return the last n password hashes of user, the newest hash is returned first
+func (h *PasswordHistory) GetRecentPasswords(userID int64, n int) ([]string, error) {
+	if h.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := h.DBContract.Query(fetchRecentPasswordsQuery, userID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := make([]string, 0)
+	for rows.Next() {
+		var password string
+		err = rows.Scan(&password)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords, rows.Err()
+}
+
+// GetRecentPasswordsContext function will return the last n password hashes of user with specific context
+func (h *PasswordHistory) GetRecentPasswordsContext(ctx context.Context, userID int64, n int) ([]string, error) {
+	if h.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := h.DBContract.QueryContext(ctx, fetchRecentPasswordsQuery, userID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := make([]string, 0)
+	for rows.Next() {
+		var password string
+		err = rows.Scan(&password)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords, rows.Err()
+}
+
+// prunePasswordHistoryQuery will keep the last n password hashes of user
+// the subquery is wrapped by derived table because mysql doesn't support LIMIT in IN subquery
+const prunePasswordHistoryQuery = `
+	DELETE FROM guard_password_history
+	WHERE user_id = ? AND id NOT IN (
+		SELECT id FROM (
+			SELECT id FROM guard_password_history
+			WHERE user_id = ?
+			ORDER BY id DESC
+			LIMIT ?
+		) recent
+	)
+`
+
+// PrunePasswordHistory function will delete the password history of user except the last n password hashes
+func (h *PasswordHistory) PrunePasswordHistory(userID int64, n int) error {
+	if h.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	_, err := h.DBContract.Exec(prunePasswordHistoryQuery, userID, userID, n)
+	return err
+}
+
+// PrunePasswordHistoryContext function will delete the password history of user except the last n password hashes with specific context
+func (h *PasswordHistory) PrunePasswordHistoryContext(ctx context.Context, userID int64, n int) error {
+	if h.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	_, err := h.DBContract.ExecContext(ctx, prunePasswordHistoryQuery, userID, userID, n)
+	return err
+}
@@ -11,6 +11,12 @@ var (
 	PermissionNotFound = errors.New("permission is not exist")
 )
 
+// EffectAllow and EffectDeny are the effect of permission that granted to role
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
 // Permission represents `guard_permission` table in the database
 type Permission struct {
 	Entity
@@ -24,6 +30,12 @@ type Permission struct {
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 
+	// RouteParams contains path params extracted from the request path when permission is resolved by resource
+	RouteParams map[string]string `json:"-"`
+
+	// Effect is the effect of permission that granted to role, it's only filled when permission is fetched by role or user
+	Effect string `db:"effect" json:"effect,omitempty"`
+
 	exist     bool                 `json:"-"`
 	validator *PermissionValidator `json:"-"`
 }
@@ -317,63 +329,104 @@ const fetchPermissionByResourceQuery = `
 		description,
 		created_at,
 		updated_at
-	FROM guard_permission WHERE method = ? AND route = ?
+	FROM guard_permission WHERE method = ? OR method = '*'
 `
 
+// scanPermissions is helper func to scan permission rows into permission collection
+func scanPermissions(rows *sql.Rows, dbContract DbContract) ([]Permission, error) {
+	defer rows.Close()
+
+	permissions := make([]Permission, 0)
+	var permission Permission
+	permission.DBContract = dbContract
+	for rows.Next() {
+		err := rows.Scan(
+			&permission.ID,
+			&permission.Name,
+			&permission.Method,
+			&permission.Route,
+			&permission.Description,
+			&permission.CreatedAt,
+			&permission.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		permission.exist = true
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// scanGrantedPermissions is helper func to scan permission rows with the effect of grant into permission collection
+func scanGrantedPermissions(rows *sql.Rows, dbContract DbContract) ([]Permission, error) {
+	defer rows.Close()
+
+	permissions := make([]Permission, 0)
+	var permission Permission
+	permission.DBContract = dbContract
+	for rows.Next() {
+		err := rows.Scan(
+			&permission.ID,
+			&permission.Name,
+			&permission.Method,
+			&permission.Route,
+			&permission.Description,
+			&permission.CreatedAt,
+			&permission.UpdatedAt,
+			&permission.Effect,
+		)
+		if err != nil {
+			return nil, err
+		}
+		permission.exist = true
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
 // GetPermissionByResource function will get the permission entity by resource
-// This function will fetch the data from database and search by method and path
+// This function will fetch the data from database and return the most specific permission that matches method and path
+// The route of permission can be a pattern like `/users/{id}` or `/files/*`, and the method can be `*`
 func (p *Permission) GetPermissionByResource(method, path string) (*Permission, error) {
 	if p.DBContract == nil {
 		return nil, ErrNoSchema
 	}
 
-	var permission = new(Permission)
-	result := p.DBContract.QueryRow(fetchPermissionByResourceQuery, method, path)
-	err := result.Scan(
-		&permission.ID,
-		&permission.Name,
-		&permission.Method,
-		&permission.Route,
-		&permission.Description,
-		&permission.CreatedAt,
-		&permission.UpdatedAt,
-	)
+	result, err := p.DBContract.Query(fetchPermissionByResourceQuery, method)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	permission.DBContract = p.DBContract
-	permission.exist = true
-	return permission, nil
+
+	permissions, err := scanPermissions(result, p.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	return MatchPermission(permissions, method, path), nil
 }
 
 // GetPermissionByResourceContext function will get the permission entity by resource with specific context
-// This function will fetch the data from database and search by method and path
+// This function will fetch the data from database and return the most specific permission that matches method and path
+// The route of permission can be a pattern like `/users/{id}` or `/files/*`, and the method can be `*`
 func (p *Permission) GetPermissionByResourceContext(ctx context.Context, method, path string) (*Permission, error) {
 	if p.DBContract == nil {
 		return nil, ErrNoSchema
 	}
 
-	var permission = new(Permission)
-	result := p.DBContract.QueryRowContext(ctx, fetchPermissionByResourceQuery, method, path)
-	err := result.Scan(
-		&permission.ID,
-		&permission.Name,
-		&permission.Method,
-		&permission.Route,
-		&permission.Description,
-		&permission.CreatedAt,
-		&permission.UpdatedAt,
-	)
+	result, err := p.DBContract.QueryContext(ctx, fetchPermissionByResourceQuery, method)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	permission.DBContract = p.DBContract
-	permission.exist = true
-	return permission, nil
+
+	permissions, err := scanPermissions(result, p.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	return MatchPermission(permissions, method, path), nil
 }
@@ -0,0 +1,307 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidPrincipal = errors.New("invalid principal of resource acl")
+)
+
+const (
+	PrincipalUser  = "user"
+	PrincipalRole  = "role"
+	PrincipalGroup = "group"
+
+	// ActionAny grants all actions on the object, e.g. for the owner of object
+	ActionAny = "*"
+)
+
+// ResourceACL represents `guard_resource_acl` table in the database
+// it grants the action on the object (resource type and resource ID) to the user, role or group.
+// the users of role and the members of group (including the members of child groups) are granted too
+type ResourceACL struct {
+	Entity
+
+	ID            int64  `db:"id" json:"id"`
+	ResourceType  string `db:"resource_type" json:"resource_type"`
+	ResourceID    string `db:"resource_id" json:"resource_id"`
+	PrincipalType string `db:"principal_type" json:"principal_type"`
+	PrincipalID   int64  `db:"principal_id" json:"principal_id"`
+	Action        string `db:"action" json:"action"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	exist bool `json:"-"`
+}
+
+// validate will validate the object, principal and action of resource acl entity
+func (a *ResourceACL) validate() error {
+	if a.ResourceType == "" || a.ResourceID == "" || a.Action == "" {
+		return ErrInvalidParams
+	}
+	switch a.PrincipalType {
+	case PrincipalUser, PrincipalRole, PrincipalGroup:
+	default:
+		return ErrInvalidPrincipal
+	}
+	if a.PrincipalID <= 0 {
+		return ErrInvalidID
+	}
+	return nil
+}
+
+const grantResourceACLQuery = `
+	INSERT INTO guard_resource_acl (
+		resource_type,
+		resource_id,
+		principal_type,
+		principal_id,
+		action,
+		created_at
+	) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
+`
+
+// Grant function will grant the action on the object to the principal
+// the existing grant will be kept, so it's safe to grant the same action twice
+func (a *ResourceACL) Grant() error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := a.validate()
+	if err != nil {
+		return err
+	}
+
+	a.CreatedAt = time.Now()
+	result, err := a.DBContract.Exec(
+		grantResourceACLQuery,
+		a.ResourceType,
+		a.ResourceID,
+		a.PrincipalType,
+		a.PrincipalID,
+		a.Action,
+		a.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	a.ID, err = result.LastInsertId()
+	a.exist = true
+	return err
+}
+
+// GrantContext function will grant the action on the object to the principal with specific context
+// the existing grant will be kept, so it's safe to grant the same action twice
+func (a *ResourceACL) GrantContext(ctx context.Context) error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := a.validate()
+	if err != nil {
+		return err
+	}
+
+	a.CreatedAt = time.Now()
+	result, err := a.DBContract.ExecContext(
+		ctx,
+		grantResourceACLQuery,
+		a.ResourceType,
+		a.ResourceID,
+		a.PrincipalType,
+		a.PrincipalID,
+		a.Action,
+		a.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	a.ID, err = result.LastInsertId()
+	a.exist = true
+	return err
+}
+
+const revokeResourceACLQuery = `
+	DELETE FROM guard_resource_acl
+	WHERE resource_type = ? AND resource_id = ? AND principal_type = ? AND principal_id = ? AND action = ?
+`
+
+// Revoke function will revoke the action on the object from the principal
+// the other actions of principal aren't revoked, e.g. revoking `read` doesn't revoke `*`
+func (a *ResourceACL) Revoke() error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := a.validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DBContract.Exec(
+		revokeResourceACLQuery,
+		a.ResourceType,
+		a.ResourceID,
+		a.PrincipalType,
+		a.PrincipalID,
+		a.Action,
+	)
+	if err != nil {
+		return err
+	}
+	a.exist = false
+	return nil
+}
+
+// RevokeContext function will revoke the action on the object from the principal with specific context
+// the other actions of principal aren't revoked, e.g. revoking `read` doesn't revoke `*`
+func (a *ResourceACL) RevokeContext(ctx context.Context) error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	err := a.validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DBContract.ExecContext(
+		ctx,
+		revokeResourceACLQuery,
+		a.ResourceType,
+		a.ResourceID,
+		a.PrincipalType,
+		a.PrincipalID,
+		a.Action,
+	)
+	if err != nil {
+		return err
+	}
+	a.exist = false
+	return nil
+}
+
+const deleteObjectACLQuery = `DELETE FROM guard_resource_acl WHERE resource_type = ? AND resource_id = ?`
+
+// RevokeObject function will revoke all grants of the object, e.g. when the object is deleted
+func (a *ResourceACL) RevokeObject(resourceType, resourceID string) error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	_, err := a.DBContract.Exec(deleteObjectACLQuery, resourceType, resourceID)
+	return err
+}
+
+// RevokeObjectContext function will revoke all grants of the object with specific context, e.g. when the object is deleted
+func (a *ResourceACL) RevokeObjectContext(ctx context.Context, resourceType, resourceID string) error {
+	if a.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	_, err := a.DBContract.ExecContext(ctx, deleteObjectACLQuery, resourceType, resourceID)
+	return err
+}
+
+const fetchResourceACLQuery = `
+	SELECT
+		id,
+		resource_type,
+		resource_id,
+		principal_type,
+		principal_id,
+		action,
+		created_at
+	FROM guard_resource_acl
+`
+
+const getObjectACLQuery = fetchResourceACLQuery + ` WHERE resource_type = ? AND resource_id = ? ORDER BY id`
+
+// GetObjectACL function will return all grants of the object
+func (a *ResourceACL) GetObjectACL(resourceType, resourceID string) ([]ResourceACL, error) {
+	if a.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := a.DBContract.Query(getObjectACLQuery, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return scanResourceACLs(rows, a.DBContract)
+}
+
+// GetObjectACLContext function will return all grants of the object with specific context
+func (a *ResourceACL) GetObjectACLContext(ctx context.Context, resourceType, resourceID string) ([]ResourceACL, error) {
+	if a.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := a.DBContract.QueryContext(ctx, getObjectACLQuery, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return scanResourceACLs(rows, a.DBContract)
+}
+
+const getPrincipalACLQuery = fetchResourceACLQuery + ` WHERE principal_type = ? AND principal_id = ? ORDER BY id`
+
+// GetPrincipalACL function will return all grants of the principal
+// the grants that inherited through roles or groups aren't included
+func (a *ResourceACL) GetPrincipalACL(principalType string, principalID int64) ([]ResourceACL, error) {
+	if a.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := a.DBContract.Query(getPrincipalACLQuery, principalType, principalID)
+	if err != nil {
+		return nil, err
+	}
+	return scanResourceACLs(rows, a.DBContract)
+}
+
+// GetPrincipalACLContext function will return all grants of the principal with specific context
+// the grants that inherited through roles or groups aren't included
+func (a *ResourceACL) GetPrincipalACLContext(ctx context.Context, principalType string, principalID int64) ([]ResourceACL, error) {
+	if a.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	rows, err := a.DBContract.QueryContext(ctx, getPrincipalACLQuery, principalType, principalID)
+	if err != nil {
+		return nil, err
+	}
+	return scanResourceACLs(rows, a.DBContract)
+}
+
+// scanResourceACLs is helper func to scan resource acl rows
+func scanResourceACLs(rows *sql.Rows, dbContract DbContract) ([]ResourceACL, error) {
+	defer rows.Close()
+
+	acls := make([]ResourceACL, 0)
+	for rows.Next() {
+		var acl ResourceACL
+		err := rows.Scan(
+			&acl.ID,
+			&acl.ResourceType,
+			&acl.ResourceID,
+			&acl.PrincipalType,
+			&acl.PrincipalID,
+			&acl.Action,
+			&acl.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		acl.DBContract = dbContract
+		acl.exist = true
+		acls = append(acls, acl)
+	}
+	return acls, rows.Err()
+}
@@ -5,10 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/dhanarJkusuma/guardian/event"
 )
 
 var (
 	RoleNotFound = errors.New("role is not exist")
+	ErrRoleCycle = errors.New("role hierarchy cannot contain a cycle")
+
+	ErrRoleAlreadyAssigned = errors.New("role is already assigned to the user")
 )
 
 // Role represents `guard_role` table in the database
@@ -24,6 +29,7 @@ type Role struct {
 
 	exist     bool           `json:"-"`
 	validator *RoleValidator `json:"-"`
+	events    *event.Bus     `json:"-"`
 }
 
 // SetValidator is setter function to set validator in role entity
@@ -31,6 +37,12 @@ func (r *Role) SetValidator(validator *RoleValidator) {
 	r.validator = validator
 }
 
+// SetEventBus is setter function to set event bus in role entity
+// the role assignment and permission changes are published to the event bus
+func (r *Role) SetEventBus(events *event.Bus) {
+	r.events = events
+}
+
 // Validate will validate all value in role entity
 func (r *Role) validate() error {
 	// validate name
@@ -245,16 +257,36 @@ func (r *Role) DeleteContext(ctx context.Context) error {
 	return nil
 }
 
+const getAssignedRoleQuery = `
+	SELECT 
+		id, 
+		valid_until 
+	FROM guard_user_role 
+	WHERE role_id = ? AND user_id = ? AND domain = ?
+`
+
 const assignRoleQuery = `
 	INSERT INTO guard_user_role (
 		role_id, 
-		user_id
-	) VALUES (?,?)
+		user_id,
+		domain,
+		valid_from,
+		valid_until
+	) VALUES (?,?,?,?,?)
 `
 
-// Assign function will assign the role to the specific user
-// This function will create a new record in the database to create relation between user and role
-func (r *Role) Assign(u *User) error {
+const reassignRoleQuery = `
+	UPDATE guard_user_role SET 
+		valid_from = ?, 
+		valid_until = ? 
+	WHERE id = ? AND valid_until IS NOT NULL
+`
+
+// assign is helper func to create a new relation between role and user in the domain, empty domain means global
+// nil validFrom and validUntil mean the relation is valid immediately and never expired.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned without period,
+// the period of existing temporary relation is replaced, e.g. to extend it or to make it permanent
+func (r *Role) assign(ctx context.Context, u *User, domain string, validFrom, validUntil *time.Time) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
@@ -271,53 +303,122 @@ func (r *Role) Assign(u *User) error {
 		return ErrInvalidID
 	}
 
-	_, err := r.DBContract.Exec(
-		assignRoleQuery,
-		r.ID,
-		u.ID,
-	)
+	var id int64
+	var assignedUntil *time.Time
+	err := r.DBContract.QueryRowContext(ctx, getAssignedRoleQuery, r.ID, u.ID, domain).Scan(&id, &assignedUntil)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = r.DBContract.ExecContext(
+			ctx,
+			assignRoleQuery,
+			r.ID,
+			u.ID,
+			domain,
+			validFrom,
+			validUntil,
+		)
+	case err != nil:
+		return err
+	case assignedUntil == nil:
+		// the permanent relation isn't replaced, so it's never deleted by the sweeper
+		return ErrRoleAlreadyAssigned
+	default:
+		_, err = r.DBContract.ExecContext(ctx, reassignRoleQuery, validFrom, validUntil, id)
+	}
 	if err != nil {
 		return err
 	}
+
+	r.events.Publish(event.RoleAssigned{
+		RoleID:     r.ID,
+		RoleName:   r.Name,
+		UserID:     u.ID,
+		Domain:     domain,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+		OccurredAt: time.Now(),
+	})
 	return nil
 }
 
-// AssignContext function will assign the role to the specific user and specific context
-// This function will create a new record in the database to create relation between user and role
-func (r *Role) AssignContext(ctx context.Context, u *User) error {
-	if r.DBContract == nil {
-		return ErrNoSchema
+// assignTemporary is helper func to create a new relation between role and user that valid in the period
+// zero validFrom means the relation is valid immediately, validUntil must be after validFrom
+func (r *Role) assignTemporary(ctx context.Context, u *User, domain string, validFrom, validUntil time.Time) error {
+	if validUntil.IsZero() || (!validFrom.IsZero() && !validUntil.After(validFrom)) {
+		return ErrInvalidParams
 	}
 
-	if !r.exist {
-		return RoleNotFound
+	var from *time.Time
+	if !validFrom.IsZero() {
+		from = &validFrom
 	}
+	return r.assign(ctx, u, domain, from, &validUntil)
+}
 
-	if !u.exist {
-		return UserNotFound
-	}
+// Assign function will assign the role to the specific user
+// This function will create a new record in the database to create relation between user and role.
+// the role is assigned globally, so it's used in every domain.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned permanently, the temporary relation is made permanent
+func (r *Role) Assign(u *User) error {
+	return r.assign(context.Background(), u, "", nil, nil)
+}
 
-	if r.ID <= 0 || u.ID <= 0 {
-		return ErrInvalidID
-	}
+// AssignContext function will assign the role to the specific user and specific context
+// This function will create a new record in the database to create relation between user and role.
+// the role is assigned globally, so it's used in every domain.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned permanently, the temporary relation is made permanent
+func (r *Role) AssignContext(ctx context.Context, u *User) error {
+	return r.assign(ctx, u, "", nil, nil)
+}
 
-	_, err := r.DBContract.ExecContext(
-		ctx,
-		assignRoleQuery,
-		r.ID,
-		u.ID,
-	)
-	if err != nil {
-		return err
-	}
-	return nil
+// AssignInDomain function will assign the role to the specific user in the domain (tenant)
+// the role is only used when the access of user is checked in the domain, see User.InDomain.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned permanently, the temporary relation is made permanent
+func (r *Role) AssignInDomain(u *User, domain string) error {
+	return r.assign(context.Background(), u, domain, nil, nil)
 }
 
-const revokeRoleQuery = `DELETE FROM guard_user_role WHERE role_id = ? AND user_id = ?`
+// AssignInDomainContext function will assign the role to the specific user in the domain (tenant) with specific context
+// the role is only used when the access of user is checked in the domain, see User.InDomain.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned permanently, the temporary relation is made permanent
+func (r *Role) AssignInDomainContext(ctx context.Context, u *User, domain string) error {
+	return r.assign(ctx, u, domain, nil, nil)
+}
 
-// Revoke function will revoke user's role by specific userID
-// This function will delete the relation between user and role
-func (r *Role) Revoke(u *User) error {
+// AssignTemporary function will assign the role to the specific user globally in the period
+// the role is used from validFrom until validUntil, zero validFrom means the role is used immediately.
+// the expired relation is deleted by RoleAssignment.SweepExpired.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned without period, the period of temporary relation is replaced
+func (r *Role) AssignTemporary(u *User, validFrom, validUntil time.Time) error {
+	return r.assignTemporary(context.Background(), u, "", validFrom, validUntil)
+}
+
+// AssignTemporaryContext function will assign the role to the specific user globally in the period with specific context
+// the role is used from validFrom until validUntil, zero validFrom means the role is used immediately.
+// the expired relation is deleted by RoleAssignment.SweepExpired.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned without period, the period of temporary relation is replaced
+func (r *Role) AssignTemporaryContext(ctx context.Context, u *User, validFrom, validUntil time.Time) error {
+	return r.assignTemporary(ctx, u, "", validFrom, validUntil)
+}
+
+// AssignTemporaryInDomain function will assign the role to the specific user in the domain (tenant) in the period
+// the role is used from validFrom until validUntil, zero validFrom means the role is used immediately.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned without period, the period of temporary relation is replaced
+func (r *Role) AssignTemporaryInDomain(u *User, domain string, validFrom, validUntil time.Time) error {
+	return r.assignTemporary(context.Background(), u, domain, validFrom, validUntil)
+}
+
+// AssignTemporaryInDomainContext function will assign the role to the specific user in the domain (tenant) in the period with specific context
+// the role is used from validFrom until validUntil, zero validFrom means the role is used immediately.
+// ErrRoleAlreadyAssigned will be returned if the role is already assigned without period, the period of temporary relation is replaced
+func (r *Role) AssignTemporaryInDomainContext(ctx context.Context, u *User, domain string, validFrom, validUntil time.Time) error {
+	return r.assignTemporary(ctx, u, domain, validFrom, validUntil)
+}
+
+const revokeRoleQuery = `DELETE FROM guard_user_role WHERE role_id = ? AND user_id = ? AND domain = ?`
+
+// revoke is helper func to delete the relation between role and user in the domain, empty domain means global
+func (r *Role) revoke(ctx context.Context, u *User, domain string) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
@@ -334,21 +435,59 @@ func (r *Role) Revoke(u *User) error {
 		return ErrInvalidID
 	}
 
-	_, err := r.DBContract.Exec(
+	_, err := r.DBContract.ExecContext(
+		ctx,
 		revokeRoleQuery,
 		r.ID,
 		u.ID,
+		domain,
 	)
 	if err != nil {
 		return err
 	}
 
+	r.events.Publish(event.RoleRevoked{
+		RoleID:     r.ID,
+		RoleName:   r.Name,
+		UserID:     u.ID,
+		Domain:     domain,
+		OccurredAt: time.Now(),
+	})
 	return nil
 }
 
-// RevokeContext function will revoke user's role by specific userID and specific context
-// This function will delete the relation between user and role
+// Revoke function will revoke user's global role by specific userID
+// This function will delete the relation between user and role, the role assigned in domain isn't revoked
+func (r *Role) Revoke(u *User) error {
+	return r.revoke(context.Background(), u, "")
+}
+
+// RevokeContext function will revoke user's global role by specific userID and specific context
+// This function will delete the relation between user and role, the role assigned in domain isn't revoked
 func (r *Role) RevokeContext(ctx context.Context, u *User) error {
+	return r.revoke(ctx, u, "")
+}
+
+// RevokeInDomain function will revoke user's role that assigned in the domain (tenant)
+func (r *Role) RevokeInDomain(u *User, domain string) error {
+	return r.revoke(context.Background(), u, domain)
+}
+
+// RevokeInDomainContext function will revoke user's role that assigned in the domain (tenant) with specific context
+func (r *Role) RevokeInDomainContext(ctx context.Context, u *User, domain string) error {
+	return r.revoke(ctx, u, domain)
+}
+
+const addPermissionQuery = `
+	INSERT INTO guard_role_permission (
+		role_id, 
+		permission_id,
+		effect
+	) VALUES (?,?,?)
+`
+
+// grantPermission is helper func to create a new relation between role with specific permission and effect
+func (r *Role) grantPermission(ctx context.Context, p *Permission, effect string) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
@@ -357,37 +496,64 @@ func (r *Role) RevokeContext(ctx context.Context, u *User) error {
 		return RoleNotFound
 	}
 
-	if !u.exist {
-		return UserNotFound
+	if !p.exist {
+		return PermissionNotFound
 	}
 
-	if r.ID <= 0 || u.ID <= 0 {
+	if r.ID <= 0 || p.ID <= 0 {
 		return ErrInvalidID
 	}
 
 	_, err := r.DBContract.ExecContext(
 		ctx,
-		revokeRoleQuery,
+		addPermissionQuery,
 		r.ID,
-		u.ID,
+		p.ID,
+		effect,
 	)
 	if err != nil {
 		return err
 	}
-
+	r.events.Publish(event.PermissionGranted{
+		RoleID:         r.ID,
+		RoleName:       r.Name,
+		PermissionID:   p.ID,
+		PermissionName: p.Name,
+		Effect:         effect,
+		OccurredAt:     time.Now(),
+	})
 	return nil
 }
 
-const addPermissionQuery = `
-	INSERT INTO guard_role_permission (
-		role_id, 
-		permission_id
-	) VALUES (?,?)
-`
-
 // AddPermission function will create a new relation between role with specific permission
 // This function will create a new record in the table relation between role and permission
 func (r *Role) AddPermission(p *Permission) error {
+	return r.grantPermission(context.Background(), p, EffectAllow)
+}
+
+// AddPermissionContext function will create a new relation between role with specific permission and specific context
+// This function will create a new record in the table relation between role and permission
+func (r *Role) AddPermissionContext(ctx context.Context, p *Permission) error {
+	return r.grantPermission(ctx, p, EffectAllow)
+}
+
+// DenyPermission function will deny the specific permission for the users of this role
+// the denied permission is decided with the allowed permissions of user's roles by the combining algorithm of schema
+func (r *Role) DenyPermission(p *Permission) error {
+	return r.grantPermission(context.Background(), p, EffectDeny)
+}
+
+// DenyPermissionContext function will deny the specific permission for the users of this role with specific context
+// the denied permission is decided with the allowed permissions of user's roles by the combining algorithm of schema
+func (r *Role) DenyPermissionContext(ctx context.Context, p *Permission) error {
+	return r.grantPermission(ctx, p, EffectDeny)
+}
+
+const removePermissionQuery = `DELETE FROM guard_role_permission WHERE role_id = ? AND permission_id = ?`
+
+// RemovePermission function will delete relation between role with specific permission
+// This function will delete relation data record in the table relation between role and permission, both allowed and denied
+func (r *Role) RemovePermission(p *Permission) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
@@ -405,19 +571,26 @@ func (r *Role) AddPermission(p *Permission) error {
 	}
 
 	_, err := r.DBContract.Exec(
-		addPermissionQuery,
+		removePermissionQuery,
 		r.ID,
 		p.ID,
 	)
 	if err != nil {
 		return err
 	}
+	r.events.Publish(event.PermissionRevoked{
+		RoleID:         r.ID,
+		RoleName:       r.Name,
+		PermissionID:   p.ID,
+		PermissionName: p.Name,
+		OccurredAt:     time.Now(),
+	})
 	return nil
 }
 
-// AddPermissionContext function will create a new relation between role with specific permission and specific context
-// This function will create a new record in the table relation between role and permission
-func (r *Role) AddPermissionContext(ctx context.Context, p *Permission) error {
+// RemovePermissionContext function will delete relation between role with specific permission and specific context
+// This function will delete relation data record in the table relation between role and permission
+func (r *Role) RemovePermissionContext(ctx context.Context, p *Permission) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
@@ -436,41 +609,118 @@ func (r *Role) AddPermissionContext(ctx context.Context, p *Permission) error {
 
 	_, err := r.DBContract.ExecContext(
 		ctx,
-		addPermissionQuery,
+		removePermissionQuery,
 		r.ID,
 		p.ID,
 	)
 	if err != nil {
 		return err
 	}
+	r.events.Publish(event.PermissionRevoked{
+		RoleID:         r.ID,
+		RoleName:       r.Name,
+		PermissionID:   p.ID,
+		PermissionName: p.Name,
+		OccurredAt:     time.Now(),
+	})
 	return nil
 }
 
-const removePermissionQuery = `DELETE FROM guard_role_permission WHERE role_id = ? AND permission_id = ?`
+const checkRoleDescendantQuery = `
+	WITH RECURSIVE descendants (role_id) AS (
+		SELECT child_id FROM guard_role_child WHERE parent_id = ?
+		UNION
+		SELECT rc.child_id FROM guard_role_child rc
+		JOIN descendants d ON rc.parent_id = d.role_id
+	)
+	SELECT EXISTS(
+		SELECT * FROM descendants WHERE role_id = ?
+	) AS is_exist
+`
 
-// RemovePermission function will delete relation between role with specific permission
-// This function will delete relation data record in the table relation between role and permission
-func (r *Role) RemovePermission(p *Permission) error {
+const addChildQuery = `
+	INSERT INTO guard_role_child (
+		parent_id, 
+		child_id
+	) VALUES (?,?)
+`
+
+// AddChild function will add the child role to this role
+// The parent role will inherit all permissions owned by child role and its descendants
+// ErrRoleCycle will be returned if this role is the child role itself or one of its descendants
+func (r *Role) AddChild(child *Role) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
 
-	if !r.exist {
+	if !r.exist || child == nil || !child.exist {
 		return RoleNotFound
 	}
 
-	if !p.exist {
-		return PermissionNotFound
+	if r.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
 	}
 
-	if r.ID <= 0 || p.ID <= 0 {
+	if r.ID == child.ID {
+		return ErrRoleCycle
+	}
+
+	var descendantRecord existRecord
+	result := r.DBContract.QueryRow(checkRoleDescendantQuery, child.ID, r.ID)
+	err := result.Scan(&descendantRecord.IsExist)
+	if err != nil {
+		return err
+	}
+	if descendantRecord.IsExist {
+		return ErrRoleCycle
+	}
+
+	_, err = r.DBContract.Exec(
+		addChildQuery,
+		r.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddChildContext function will add the child role to this role with specific context
+// The parent role will inherit all permissions owned by child role and its descendants
+// ErrRoleCycle will be returned if this role is the child role itself or one of its descendants
+func (r *Role) AddChildContext(ctx context.Context, child *Role) error {
+	if r.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !r.exist || child == nil || !child.exist {
+		return RoleNotFound
+	}
+
+	if r.ID <= 0 || child.ID <= 0 {
 		return ErrInvalidID
 	}
 
-	_, err := r.DBContract.Exec(
-		removePermissionQuery,
+	if r.ID == child.ID {
+		return ErrRoleCycle
+	}
+
+	var descendantRecord existRecord
+	result := r.DBContract.QueryRowContext(ctx, checkRoleDescendantQuery, child.ID, r.ID)
+	err := result.Scan(&descendantRecord.IsExist)
+	if err != nil {
+		return err
+	}
+	if descendantRecord.IsExist {
+		return ErrRoleCycle
+	}
+
+	_, err = r.DBContract.ExecContext(
+		ctx,
+		addChildQuery,
 		r.ID,
-		p.ID,
+		child.ID,
 	)
 	if err != nil {
 		return err
@@ -478,30 +728,52 @@ func (r *Role) RemovePermission(p *Permission) error {
 	return nil
 }
 
-// RemovePermissionContext function will delete relation between role with specific permission and specific context
-// This function will delete relation data record in the table relation between role and permission
-func (r *Role) RemovePermissionContext(ctx context.Context, p *Permission) error {
+const removeChildQuery = `DELETE FROM guard_role_child WHERE parent_id = ? AND child_id = ?`
+
+// RemoveChild function will remove the child role from this role
+func (r *Role) RemoveChild(child *Role) error {
 	if r.DBContract == nil {
 		return ErrNoSchema
 	}
 
-	if !r.exist {
+	if !r.exist || child == nil || !child.exist {
 		return RoleNotFound
 	}
 
-	if !p.exist {
-		return PermissionNotFound
+	if r.ID <= 0 || child.ID <= 0 {
+		return ErrInvalidID
 	}
 
-	if r.ID <= 0 || p.ID <= 0 {
+	_, err := r.DBContract.Exec(
+		removeChildQuery,
+		r.ID,
+		child.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveChildContext function will remove the child role from this role with specific context
+func (r *Role) RemoveChildContext(ctx context.Context, child *Role) error {
+	if r.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if !r.exist || child == nil || !child.exist {
+		return RoleNotFound
+	}
+
+	if r.ID <= 0 || child.ID <= 0 {
 		return ErrInvalidID
 	}
 
 	_, err := r.DBContract.ExecContext(
 		ctx,
-		removePermissionQuery,
+		removeChildQuery,
 		r.ID,
-		p.ID,
+		child.ID,
 	)
 	if err != nil {
 		return err
@@ -509,6 +781,96 @@ func (r *Role) RemovePermissionContext(ctx context.Context, p *Permission) error
 	return nil
 }
 
+const getChildrenQuery = `
+	SELECT
+		r.id,
+		r.name,
+		r.description,
+		r.created_at,
+		r.updated_at
+	FROM guard_role r
+	JOIN guard_role_child rc ON rc.child_id = r.id
+	WHERE rc.parent_id = ?
+`
+
+// GetChildren function will return the direct child roles of this role
+func (r *Role) GetChildren() ([]Role, error) {
+	if r.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !r.exist {
+		return nil, RoleNotFound
+	}
+
+	roles := make([]Role, 0)
+	result, err := r.DBContract.Query(getChildrenQuery, r.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return roles, nil
+		}
+		return nil, err
+	}
+	defer result.Close()
+
+	var role Role
+	role.DBContract = r.DBContract
+	for result.Next() {
+		err = result.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		role.exist = true
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// GetChildrenContext function will return the direct child roles of this role with specific context
+func (r *Role) GetChildrenContext(ctx context.Context) ([]Role, error) {
+	if r.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !r.exist {
+		return nil, RoleNotFound
+	}
+
+	roles := make([]Role, 0)
+	result, err := r.DBContract.QueryContext(ctx, getChildrenQuery, r.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return roles, nil
+		}
+		return nil, err
+	}
+	defer result.Close()
+
+	var role Role
+	role.DBContract = r.DBContract
+	for result.Next() {
+		err = result.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		role.exist = true
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
 const getPermissionQuery = `
 	SELECT
 		p.id,
@@ -517,13 +879,15 @@ const getPermissionQuery = `
 		p.route,
 		p.description,
 		p.created_at,
-		p.updated_at
+		p.updated_at,
+		rp.effect
 	FROM guard_permission p
 	JOIN guard_role_permission rp ON rp.permission_id = p.id   
 	WHERE rp.role_id = ?
 `
 
 // GetPermissions function will return the permission collection by specific role
+// Effect of each permission is filled with EffectAllow or EffectDeny
 func (r *Role) GetPermissions() ([]Permission, error) {
 	if r.DBContract == nil {
 		return nil, ErrNoSchema
@@ -550,6 +914,7 @@ func (r *Role) GetPermissions() ([]Permission, error) {
 			&permission.Description,
 			&permission.CreatedAt,
 			&permission.UpdatedAt,
+			&permission.Effect,
 		)
 		if err == nil {
 			permission.exist = true
@@ -584,6 +949,7 @@ func (r *Role) GetPermissionsContext(ctx context.Context) ([]Permission, error)
 			&permission.Description,
 			&permission.CreatedAt,
 			&permission.UpdatedAt,
+			&permission.Effect,
 		)
 		if err == nil {
 			permission.exist = true
@@ -655,22 +1021,46 @@ func (r *Role) GetRoleContext(ctx context.Context, name string) (*Role, error) {
 	return role, nil
 }
 
-const fetchRolesResourceQuery = `
-	SELECT 
+const fetchRolesResourceQuery = userRolesCTE + `
+	SELECT DISTINCT
 		r.id,
 		r.name,
 		r.description,
 		r.created_at,
 		r.updated_at
 	FROM guard_role r
+	JOIN user_roles ur ON ur.role_id = r.id
 	JOIN guard_role_permission rp ON rp.role_id = r.id
-	JOIN guard_permission p ON p.id = rp.permission_id
-	JOIN guard_user_role ur ON ur.role_id = r.id
-	WHERE ur.user_id = ? AND p.method = ?  AND p.route = ?
+	WHERE rp.permission_id = ? AND rp.effect = 'allow'
 `
 
+// scanRoles is helper func to scan role rows into role collection
+func scanRoles(rows *sql.Rows, dbContract DbContract) ([]Role, error) {
+	defer rows.Close()
+
+	roles := make([]Role, 0)
+	var role Role
+	role.DBContract = dbContract
+	for rows.Next() {
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		role.exist = true
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
 // GetRolesResource function will return a collection of roles that associated with user, method, and route
-// This function will fetch the data from database and search by user_id, method, and route
+// This function will resolve the most specific permission owned by user that matches method and route,
+// then return all user's roles (including the inherited roles) that grant the permission
 func (r *Role) GetRolesResource(user *User, method, route string) ([]Role, error) {
 	if r.DBContract == nil {
 		return nil, ErrNoSchema
@@ -680,44 +1070,40 @@ func (r *Role) GetRolesResource(user *User, method, route string) ([]Role, error
 		return nil, ErrInvalidID
 	}
 
-	if !r.exist {
-		return nil, RoleNotFound
-	}
-
 	if !user.exist {
 		return nil, UserNotFound
 	}
 
-	var role Role
-	role.DBContract = r.DBContract
 	roles := make([]Role, 0)
-	result, err := r.DBContract.Query(fetchRolesResourceQuery, user.ID, method, route)
+	result, err := r.DBContract.Query(getAccessQuery, user.userRolesParams(method)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return roles, nil
 		}
 		return nil, err
 	}
-	for result.Next() {
-		err := result.Scan(
-			&role.ID,
-			&role.Name,
-			&role.Description,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		role.exist = true
-		roles = append(roles, role)
+	permissions, err := scanGrantedPermissions(result, r.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	permission := ResolvePermission(permissions, method, route, user.combining)
+	if permission == nil {
+		return roles, nil
 	}
 
-	return roles, nil
+	result, err = r.DBContract.Query(fetchRolesResourceQuery, user.userRolesParams(permission.ID)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return roles, nil
+		}
+		return nil, err
+	}
+	return scanRoles(result, r.DBContract)
 }
 
-// GetRolesResource function will return a collection of roles that associated with user, method, and route
-// This function will fetch the data from database and search by user_id, method, and route
+// GetRolesResourceContext function will return a collection of roles that associated with user, method, and route
+// This function will resolve the most specific permission owned by user that matches method and route with specific context,
+// then return all user's roles (including the inherited roles) that grant the permission
 func (r *Role) GetRolesResourceContext(ctx context.Context, user *User, method, route string) ([]Role, error) {
 	if r.DBContract == nil {
 		return nil, ErrNoSchema
@@ -727,44 +1113,33 @@ func (r *Role) GetRolesResourceContext(ctx context.Context, user *User, method,
 		return nil, ErrInvalidID
 	}
 
-	if !r.exist {
-		return nil, RoleNotFound
-	}
-
 	if !user.exist {
 		return nil, UserNotFound
 	}
 
-	var role Role
-	role.DBContract = r.DBContract
 	roles := make([]Role, 0)
-	result, err := r.DBContract.QueryContext(
-		ctx,
-		fetchRolesResourceQuery,
-		user.ID,
-		method,
-		route,
-	)
+	result, err := r.DBContract.QueryContext(ctx, getAccessQuery, user.userRolesParams(method)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return roles, nil
 		}
 		return nil, err
 	}
-	for result.Next() {
-		err := result.Scan(
-			&role.ID,
-			&role.Name,
-			&role.Description,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		role.exist = true
-		roles = append(roles, role)
+	permissions, err := scanGrantedPermissions(result, r.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	permission := ResolvePermission(permissions, method, route, user.combining)
+	if permission == nil {
+		return roles, nil
 	}
 
-	return roles, nil
+	result, err = r.DBContract.QueryContext(ctx, fetchRolesResourceQuery, user.userRolesParams(permission.ID)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return roles, nil
+		}
+		return nil, err
+	}
+	return scanRoles(result, r.DBContract)
 }
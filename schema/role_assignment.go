@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/dhanarJkusuma/guardian/event"
+)
+
+// RoleAssignment represents `guard_user_role` table in the database
+// the role is assigned temporarily if ValidUntil isn't nil, see Role.AssignTemporary
+type RoleAssignment struct {
+	Entity
+
+	ID         int64      `db:"id" json:"id"`
+	RoleID     int64      `db:"role_id" json:"role_id"`
+	RoleName   string     `db:"role_name" json:"role_name"`
+	UserID     int64      `db:"user_id" json:"user_id"`
+	Domain     string     `db:"domain" json:"domain"`
+	ValidFrom  *time.Time `db:"valid_from" json:"valid_from"`
+	ValidUntil *time.Time `db:"valid_until" json:"valid_until"`
+
+	events *event.Bus `json:"-"`
+}
+
+// SetEventBus is setter function to set event bus in role assignment entity
+// the expired role assignments that deleted by SweepExpired are published to the event bus
+func (a *RoleAssignment) SetEventBus(events *event.Bus) {
+	a.events = events
+}
+
+const fetchRoleAssignmentQuery = `
+	SELECT
+		ur.id,
+		ur.role_id,
+		r.name,
+		ur.user_id,
+		ur.domain,
+		ur.valid_from,
+		ur.valid_until
+	FROM guard_user_role ur
+	JOIN guard_role r ON r.id = ur.role_id
+`
+
+const getExpiringAssignmentsQuery = fetchRoleAssignmentQuery + `
+	WHERE ur.valid_until > ? AND ur.valid_until <= ?
+	ORDER BY ur.valid_until
+`
+
+// getExpiring is helper func to get the temporary role assignments that will expire within the duration
+func (a *RoleAssignment) getExpiring(ctx context.Context, within time.Duration) ([]RoleAssignment, error) {
+	if a.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	now := time.Now()
+	rows, err := a.DBContract.QueryContext(ctx, getExpiringAssignmentsQuery, now, now.Add(within))
+	if err != nil {
+		return nil, err
+	}
+	return scanRoleAssignments(rows, a.DBContract)
+}
+
+// GetExpiring function will return the temporary role assignments that will expire within the duration
+// the assignments are ordered by the expiration time, the expired assignments aren't included
+func (a *RoleAssignment) GetExpiring(within time.Duration) ([]RoleAssignment, error) {
+	return a.getExpiring(context.Background(), within)
+}
+
+// GetExpiringContext function will return the temporary role assignments that will expire within the duration with specific context
+// the assignments are ordered by the expiration time, the expired assignments aren't included
+func (a *RoleAssignment) GetExpiringContext(ctx context.Context, within time.Duration) ([]RoleAssignment, error) {
+	return a.getExpiring(ctx, within)
+}
+
+const getExpiredAssignmentsQuery = fetchRoleAssignmentQuery + ` WHERE ur.valid_until <= ?`
+
+const deleteExpiredAssignmentQuery = `DELETE FROM guard_user_role WHERE id = ? AND valid_until <= ?`
+
+// sweepExpired is helper func to delete the expired role assignments and publish event.RoleExpired
+func (a *RoleAssignment) sweepExpired(ctx context.Context) (int64, error) {
+	if a.DBContract == nil {
+		return 0, ErrNoSchema
+	}
+
+	now := time.Now()
+	rows, err := a.DBContract.QueryContext(ctx, getExpiredAssignmentsQuery, now)
+	if err != nil {
+		return 0, err
+	}
+	expired, err := scanRoleAssignments(rows, a.DBContract)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, assignment := range expired {
+		// the assignment that extended after it's fetched isn't deleted
+		result, err := a.DBContract.ExecContext(ctx, deleteExpiredAssignmentQuery, assignment.ID, now)
+		if err != nil {
+			return deleted, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		if affected == 0 {
+			continue
+		}
+
+		deleted++
+		a.events.Publish(event.RoleExpired{
+			RoleID:     assignment.RoleID,
+			RoleName:   assignment.RoleName,
+			UserID:     assignment.UserID,
+			Domain:     assignment.Domain,
+			ExpiredAt:  *assignment.ValidUntil,
+			OccurredAt: time.Now(),
+		})
+	}
+	return deleted, nil
+}
+
+// SweepExpired function will delete the expired role assignments and return the number of deleted assignments
+// event.RoleExpired is published for every deleted assignment
+func (a *RoleAssignment) SweepExpired() (int64, error) {
+	return a.sweepExpired(context.Background())
+}
+
+// SweepExpiredContext function will delete the expired role assignments with specific context and return the number of deleted assignments
+// event.RoleExpired is published for every deleted assignment
+func (a *RoleAssignment) SweepExpiredContext(ctx context.Context) (int64, error) {
+	return a.sweepExpired(ctx)
+}
+
+// scanRoleAssignments is helper func to scan role assignment rows
+func scanRoleAssignments(rows *sql.Rows, dbContract DbContract) ([]RoleAssignment, error) {
+	defer rows.Close()
+
+	assignments := make([]RoleAssignment, 0)
+	for rows.Next() {
+		var assignment RoleAssignment
+		err := rows.Scan(
+			&assignment.ID,
+			&assignment.RoleID,
+			&assignment.RoleName,
+			&assignment.UserID,
+			&assignment.Domain,
+			&assignment.ValidFrom,
+			&assignment.ValidUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		assignment.DBContract = dbContract
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// AssignmentSweeper will delete the expired role assignments periodically by the background goroutine
+type AssignmentSweeper struct {
+	assignment *RoleAssignment
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewAssignmentSweeper acts as constructor, interval is the interval of expired role assignment sweeping
+// if interval <= 0, the background goroutine isn't started and Sweep must be called manually
+func NewAssignmentSweeper(assignment *RoleAssignment, interval time.Duration) *AssignmentSweeper {
+	sweeper := &AssignmentSweeper{
+		assignment: assignment,
+		stop:       make(chan struct{}),
+	}
+	if interval > 0 {
+		go sweeper.run(interval)
+	}
+	return sweeper
+}
+
+// run will run Sweep in every interval until the sweeper is closed
+func (s *AssignmentSweeper) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Sweep will delete the expired role assignments, see RoleAssignment.SweepExpired
+func (s *AssignmentSweeper) Sweep() (int64, error) {
+	return s.assignment.SweepExpired()
+}
+
+// Close will stop the background sweeper
+func (s *AssignmentSweeper) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
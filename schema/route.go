@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// AnyMethod is permission method that matches every http method
+	AnyMethod = "*"
+
+	routeWildcard = "*"
+)
+
+// segment kinds, ordered by the specificity
+const (
+	segmentRest = iota
+	segmentWildcard
+	segmentParam
+	segmentLiteral
+)
+
+// splitRoute is helper func to split route or path into segments
+func splitRoute(route string) []string {
+	route = strings.Trim(route, "/")
+	if route == "" {
+		return []string{}
+	}
+	return strings.Split(route, "/")
+}
+
+// isParamSegment will check the segment is path parameter like `{id}` or not
+func isParamSegment(segment string) bool {
+	return len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// segmentKinds will return the kind of each segment in the route pattern
+func segmentKinds(pattern string) []int {
+	segments := splitRoute(pattern)
+	kinds := make([]int, len(segments))
+	for i, segment := range segments {
+		switch {
+		case segment == routeWildcard && i == len(segments)-1:
+			kinds[i] = segmentRest
+		case segment == routeWildcard:
+			kinds[i] = segmentWildcard
+		case isParamSegment(segment):
+			kinds[i] = segmentParam
+		default:
+			kinds[i] = segmentLiteral
+		}
+	}
+	return kinds
+}
+
+// MatchMethod will check the permission method pattern matches the request method or not
+func MatchMethod(pattern, method string) bool {
+	return pattern == AnyMethod || strings.EqualFold(pattern, method)
+}
+
+// MatchRoute will match the request path with the route pattern and return the extracted path params
+// The pattern supports `{name}` to capture one segment, `*` in the middle to match one segment,
+// and trailing `*` to match the rest of path (at least one segment)
+// Example: `/users/{id}` matches `/users/42` with params {"id": "42"}, and `/files/*` matches `/files/a/b.txt`
+func MatchRoute(pattern, path string) (map[string]string, bool) {
+	patternSegments := splitRoute(pattern)
+	pathSegments := splitRoute(path)
+	params := make(map[string]string)
+
+	for i, segment := range patternSegments {
+		// trailing wildcard will match the rest of path
+		if segment == routeWildcard && i == len(patternSegments)-1 {
+			if len(pathSegments) <= i {
+				return nil, false
+			}
+			return params, true
+		}
+
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+
+		switch {
+		case segment == routeWildcard:
+			continue
+		case isParamSegment(segment):
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+
+	if len(pathSegments) != len(patternSegments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// comparePermission will compare the specificity of two permissions
+// It'll return positive number if a is more specific than b, negative number if b is more specific than a
+// literal segment wins over path param, path param wins over wildcard, and longer route wins over shorter one.
+// If the routes have same specificity, exact method wins over `*` method, then the lower ID wins, then deny wins over allow
+func comparePermission(a, b *Permission) int {
+	aKinds := segmentKinds(a.Route)
+	bKinds := segmentKinds(b.Route)
+	for i := 0; i < len(aKinds) && i < len(bKinds); i++ {
+		if aKinds[i] != bKinds[i] {
+			return aKinds[i] - bKinds[i]
+		}
+	}
+	if len(aKinds) != len(bKinds) {
+		return len(aKinds) - len(bKinds)
+	}
+
+	aExactMethod := a.Method != AnyMethod
+	bExactMethod := b.Method != AnyMethod
+	if aExactMethod != bExactMethod {
+		if aExactMethod {
+			return 1
+		}
+		return -1
+	}
+
+	switch {
+	case a.ID < b.ID:
+		return 1
+	case a.ID > b.ID:
+		return -1
+	}
+
+	// the same permission that granted by different roles, deny wins over allow
+	aDeny := a.Effect == EffectDeny
+	bDeny := b.Effect == EffectDeny
+	if aDeny != bDeny {
+		if aDeny {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// MatchPermissions will return all permissions that match the method and path
+// The result is sorted from the most specific permission, and RouteParams of each permission will be filled
+func MatchPermissions(permissions []Permission, method, path string) []Permission {
+	matched := make([]Permission, 0)
+	for _, permission := range permissions {
+		if !MatchMethod(permission.Method, method) {
+			continue
+		}
+		params, ok := MatchRoute(permission.Route, path)
+		if !ok {
+			continue
+		}
+		permission.RouteParams = params
+		matched = append(matched, permission)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return comparePermission(&matched[i], &matched[j]) > 0
+	})
+	return matched
+}
+
+// MatchPermission will return the most specific permission that match the method and path
+// nil will be returned if there's no permission matched
+func MatchPermission(permissions []Permission, method, path string) *Permission {
+	matched := MatchPermissions(permissions, method, path)
+	if len(matched) == 0 {
+		return nil
+	}
+	return &matched[0]
+}
+
+// CombiningAlgorithm decides the access when the matched permissions of user have different effects
+type CombiningAlgorithm int
+
+const (
+	// DenyOverrides will reject the access if any matched permission is denied, even if the allowed permission is more specific
+	DenyOverrides CombiningAlgorithm = 0
+	// FirstApplicable will decide the access by the most specific matched permission, see MatchPermissions for the order
+	FirstApplicable CombiningAlgorithm = 1
+)
+
+// ResolvePermission will return the most specific allowed permission that match the method and path by combining algorithm
+// nil will be returned if there's no permission matched or the access is denied
+func ResolvePermission(permissions []Permission, method, path string, algorithm CombiningAlgorithm) *Permission {
+	matched := MatchPermissions(permissions, method, path)
+	if len(matched) == 0 {
+		return nil
+	}
+
+	if algorithm == FirstApplicable {
+		if matched[0].Effect == EffectDeny {
+			return nil
+		}
+		return &matched[0]
+	}
+
+	var allowed *Permission
+	for i := range matched {
+		if matched[i].Effect == EffectDeny {
+			return nil
+		}
+		if allowed == nil {
+			allowed = &matched[i]
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestMatchRoute(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+		params  map[string]string
+	}{
+		{"/users", "/users", true, map[string]string{}},
+		{"/users", "/users/", true, map[string]string{}},
+		{"/users", "/accounts", false, nil},
+		{"/users/{id}", "/users/42", true, map[string]string{"id": "42"}},
+		{"/users/{id}", "/users", false, nil},
+		{"/users/{id}", "/users/42/roles", false, nil},
+		{"/users/{id}/roles/{role}", "/users/42/roles/admin", true, map[string]string{"id": "42", "role": "admin"}},
+		{"/users/*/roles", "/users/42/roles", true, map[string]string{}},
+		{"/users/*/roles", "/users/42/groups", false, nil},
+		{"/files/*", "/files/a/b.txt", true, map[string]string{}},
+		{"/files/*", "/files", false, nil},
+		{"/{}", "/{}", true, map[string]string{}},
+	}
+
+	for _, c := range cases {
+		params, ok := MatchRoute(c.pattern, c.path)
+		if ok != c.matched {
+			t.Fatalf("%s with %s: expected matched %v, got %v", c.pattern, c.path, c.matched, ok)
+		}
+		if !ok {
+			continue
+		}
+		if len(params) != len(c.params) {
+			t.Fatalf("%s with %s: expected params %v, got %v", c.pattern, c.path, c.params, params)
+		}
+		for name, value := range c.params {
+			if params[name] != value {
+				t.Fatalf("%s with %s: expected params %v, got %v", c.pattern, c.path, c.params, params)
+			}
+		}
+	}
+}
+
+// matchedIDs is helper func to return the IDs of matched permissions in order
+func matchedIDs(permissions []Permission) []int64 {
+	ids := make([]int64, len(permissions))
+	for i, permission := range permissions {
+		ids[i] = permission.ID
+	}
+	return ids
+}
+
+func TestMatchPermissionsMostSpecificWins(t *testing.T) {
+	permissions := []Permission{
+		{ID: 1, Method: AnyMethod, Route: "/*"},
+		{ID: 2, Method: "GET", Route: "/users/*"},
+		{ID: 3, Method: "GET", Route: "/users/{id}"},
+		{ID: 4, Method: "GET", Route: "/users/me"},
+		{ID: 5, Method: AnyMethod, Route: "/users/me"},
+		{ID: 6, Method: "POST", Route: "/users/{id}"},
+		{ID: 7, Method: "GET", Route: "/users/*/roles"},
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		ids    []int64
+	}{
+		// exact route wins over path param, path param wins over wildcard, exact method wins over `*`
+		{"GET", "/users/me", []int64{4, 5, 3, 2, 1}},
+		{"get", "/users/me", []int64{4, 5, 3, 2, 1}},
+		{"GET", "/users/42", []int64{3, 2, 1}},
+		{"POST", "/users/42", []int64{6, 1}},
+		{"DELETE", "/users/me", []int64{5, 1}},
+		// middle wildcard is more specific than trailing wildcard
+		{"GET", "/users/42/roles", []int64{7, 2, 1}},
+		{"GET", "/groups", []int64{1}},
+		{"GET", "/", []int64{}},
+	}
+
+	for _, c := range cases {
+		ids := matchedIDs(MatchPermissions(permissions, c.method, c.path))
+		if len(ids) != len(c.ids) {
+			t.Fatalf("%s %s: expected %v, got %v", c.method, c.path, c.ids, ids)
+		}
+		for i := range ids {
+			if ids[i] != c.ids[i] {
+				t.Fatalf("%s %s: expected %v, got %v", c.method, c.path, c.ids, ids)
+			}
+		}
+	}
+
+	// the input order doesn't change the result
+	reversed := make([]Permission, len(permissions))
+	for i, permission := range permissions {
+		reversed[len(permissions)-1-i] = permission
+	}
+	ids := matchedIDs(MatchPermissions(reversed, "GET", "/users/me"))
+	if len(ids) != 5 || ids[0] != 4 || ids[1] != 5 || ids[2] != 3 {
+		t.Fatalf("reversed permissions: expected [4 5 3 2 1], got %v", ids)
+	}
+
+	// the route params of each matched permission are filled by its own route
+	matched := MatchPermission(permissions, "GET", "/users/42")
+	if matched == nil || matched.ID != 3 || matched.RouteParams["id"] != "42" {
+		t.Fatalf("expected permission 3 with id param, got %+v", matched)
+	}
+	if MatchPermission(permissions, "GET", "/") != nil {
+		t.Fatalf("expected no permission matched")
+	}
+}
+
+func TestMatchPermissionsSameRoute(t *testing.T) {
+	// the same route and method, the lower ID wins, then deny wins over allow of the same permission
+	permissions := []Permission{
+		{ID: 9, Method: "GET", Route: "/reports/{id}", Effect: EffectAllow},
+		{ID: 8, Method: "GET", Route: "/reports/{id}", Effect: EffectAllow},
+		{ID: 8, Method: "GET", Route: "/reports/{id}", Effect: EffectDeny},
+	}
+
+	matched := MatchPermissions(permissions, "GET", "/reports/1")
+	if len(matched) != 3 {
+		t.Fatalf("expected 3 permissions matched, got %d", len(matched))
+	}
+	if matched[0].ID != 8 || matched[0].Effect != EffectDeny {
+		t.Fatalf("expected denied permission 8 first, got %d %s", matched[0].ID, matched[0].Effect)
+	}
+	if matched[1].ID != 8 || matched[2].ID != 9 {
+		t.Fatalf("expected permission 8 before 9, got %v", matchedIDs(matched))
+	}
+}
@@ -356,19 +356,21 @@ func (r *Rule) GetRolesRule(roles []Role) ([]Rule, error) {
 		return nil, ErrNoSchema
 	}
 
-	args := make([]interface{}, len(roles))
-	args[0] = EnumRuleTypes.RoleRuleType
+	rules := make([]Rule, 0)
+	args := []interface{}{EnumRuleTypes.RoleRuleType}
 	for i := range roles {
 		if roles[i].exist {
 			args = append(args, roles[i].ID)
 		}
 	}
-	inStmt := `(?` + strings.Repeat(",?", len(roles)-1) + `)`
+	if len(args) == 1 {
+		return rules, nil
+	}
+	inStmt := `(?` + strings.Repeat(",?", len(args)-2) + `)`
 	query := strings.Replace(fetchRuleByRuleTypeAndParentIDs, `(?)`, inStmt, -1)
 
 	var rule Rule
 	rule.DBContract = r.DBContract
-	rules := make([]Rule, 0)
 	result, err := r.DBContract.Query(query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -401,19 +403,21 @@ func (r *Rule) GetRolesRuleContext(ctx context.Context, roles []Role) ([]Rule, e
 		return nil, ErrNoSchema
 	}
 
-	args := make([]interface{}, len(roles))
-	args[0] = EnumRuleTypes.RoleRuleType
+	rules := make([]Rule, 0)
+	args := []interface{}{EnumRuleTypes.RoleRuleType}
 	for i := range roles {
 		if roles[i].exist {
 			args = append(args, roles[i].ID)
 		}
 	}
-	inStmt := `(?` + strings.Repeat(",?", len(args)-1) + `)`
+	if len(args) == 1 {
+		return rules, nil
+	}
+	inStmt := `(?` + strings.Repeat(",?", len(args)-2) + `)`
 	query := strings.Replace(fetchRuleByRuleTypeAndParentIDs, `(?)`, inStmt, -1)
 
 	var rule Rule
 	rule.DBContract = r.DBContract
-	rules := make([]Rule, 0)
 	result, err := r.DBContract.QueryContext(ctx, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
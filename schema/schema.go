@@ -4,11 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/dhanarJkusuma/guardian/event"
 )
 
 type Schema struct {
 	DbConnection *sql.DB
 	Validator    *Validator
+
+	// Events is used to publish the role and permission changes, the events aren't published if it's nil
+	Events *event.Bus
+
+	// CombiningAlgorithm decides the access of user when the permissions of user's roles are allowed and denied
+	CombiningAlgorithm CombiningAlgorithm
 }
 
 type Entity struct {
@@ -44,11 +52,13 @@ func (s *Schema) User(userModel *User) *User {
 		return &User{
 			Entity:    Entity{DBContract: s.DbConnection},
 			validator: s.Validator.User,
+			combining: s.CombiningAlgorithm,
 		}
 	}
 
 	userModel.DBContract = s.DbConnection
 	userModel.validator = s.Validator.User
+	userModel.combining = s.CombiningAlgorithm
 	return userModel
 }
 
@@ -73,13 +83,43 @@ func (s *Schema) Role(roleModel *Role) *Role {
 		return &Role{
 			Entity:    Entity{DBContract: s.DbConnection},
 			validator: s.Validator.Role,
+			events:    s.Events,
 		}
 	}
 	roleModel.DBContract = s.DbConnection
 	roleModel.validator = s.Validator.Role
+	roleModel.events = s.Events
 	return roleModel
 }
 
+// Group function will inject schema in the groupModel
+// This function will inject the database connection to groupModel
+func (s *Schema) Group(groupModel *Group) *Group {
+	if groupModel == nil {
+		return &Group{
+			Entity:    Entity{DBContract: s.DbConnection},
+			validator: s.Validator.Group,
+		}
+	}
+	groupModel.DBContract = s.DbConnection
+	groupModel.validator = s.Validator.Group
+	return groupModel
+}
+
+// RoleAssignment function will inject schema in the roleAssignmentModel
+// This function will inject the database connection to roleAssignmentModel
+func (s *Schema) RoleAssignment(roleAssignmentModel *RoleAssignment) *RoleAssignment {
+	if roleAssignmentModel == nil {
+		return &RoleAssignment{
+			Entity: Entity{DBContract: s.DbConnection},
+			events: s.Events,
+		}
+	}
+	roleAssignmentModel.DBContract = s.DbConnection
+	roleAssignmentModel.events = s.Events
+	return roleAssignmentModel
+}
+
 // Rule function will inject schema in the ruleModel
 // This function will inject the database connection to ruleModel
 func (s *Schema) Rule(ruleModel *Rule) *Rule {
@@ -93,3 +133,75 @@ func (s *Schema) Rule(ruleModel *Rule) *Rule {
 	ruleModel.validator = s.Validator.Rule
 	return ruleModel
 }
+
+// ResourceACL function will inject schema in the resourceACLModel
+// This function will inject the database connection to resourceACLModel
+func (s *Schema) ResourceACL(resourceACLModel *ResourceACL) *ResourceACL {
+	if resourceACLModel == nil {
+		return &ResourceACL{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	resourceACLModel.DBContract = s.DbConnection
+	return resourceACLModel
+}
+
+// UserMFA function will inject schema in the userMFAModel
+// This function will inject the database connection to userMFAModel
+func (s *Schema) UserMFA(userMFAModel *UserMFA) *UserMFA {
+	if userMFAModel == nil {
+		return &UserMFA{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	userMFAModel.DBContract = s.DbConnection
+	return userMFAModel
+}
+
+// APIKey function will inject schema in the apiKeyModel
+// This function will inject the database connection to apiKeyModel
+func (s *Schema) APIKey(apiKeyModel *APIKey) *APIKey {
+	if apiKeyModel == nil {
+		return &APIKey{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	apiKeyModel.DBContract = s.DbConnection
+	return apiKeyModel
+}
+
+// UserIdentity function will inject schema in the userIdentityModel
+// This function will inject the database connection to userIdentityModel
+func (s *Schema) UserIdentity(userIdentityModel *UserIdentity) *UserIdentity {
+	if userIdentityModel == nil {
+		return &UserIdentity{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	userIdentityModel.DBContract = s.DbConnection
+	return userIdentityModel
+}
+
+// PasswordHistory function will inject schema in the passwordHistoryModel
+// This function will inject the database connection to passwordHistoryModel
+func (s *Schema) PasswordHistory(passwordHistoryModel *PasswordHistory) *PasswordHistory {
+	if passwordHistoryModel == nil {
+		return &PasswordHistory{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	passwordHistoryModel.DBContract = s.DbConnection
+	return passwordHistoryModel
+}
+
+// AuditLog function will inject schema in the auditLogModel
+// This function will inject the database connection to auditLogModel
+func (s *Schema) AuditLog(auditLogModel *AuditLog) *AuditLog {
+	if auditLogModel == nil {
+		return &AuditLog{
+			Entity: Entity{DBContract: s.DbConnection},
+		}
+	}
+	auditLogModel.DBContract = s.DbConnection
+	return auditLogModel
+}
@@ -22,12 +22,20 @@ type User struct {
 	Password string `db:"password" json:"-"`
 	Active   bool   `db:"active" json:"active"`
 
+	// EmailVerifiedAt is nil if user hasn't verified the email
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"`
+
+	// PasswordChangedAt is nil if user hasn't changed the password since registered
+	PasswordChangedAt *time.Time `db:"password_changed_at" json:"password_changed_at"`
+
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 
-	exist             bool           `json:"-"`
-	passwordEncrypted bool           `json:"-"`
-	validator         *UserValidator `json:"-"`
+	exist             bool               `json:"-"`
+	passwordEncrypted bool               `json:"-"`
+	validator         *UserValidator     `json:"-"`
+	combining         CombiningAlgorithm `json:"-"`
+	domain            string             `json:"-"`
 }
 
 // setDefaultTimeStamp is helper func to set current time for attribute `created_at` and `updated_at`
@@ -72,12 +80,7 @@ func (u *User) Validate() error {
 
 	// password length
 	if !u.passwordEncrypted {
-		err = u.validator.Password.StringValidator.validateLen("password", u.Password)
-		if err != nil {
-			return err
-		}
-
-		err = u.validator.Password.Regex.validateRegex("password", u.Password)
+		err = u.ValidatePassword(u.Password)
 		if err != nil {
 			return err
 		}
@@ -86,6 +89,16 @@ func (u *User) Validate() error {
 	return nil
 }
 
+// ValidatePassword will validate the plain password with the password validator
+func (u *User) ValidatePassword(password string) error {
+	err := u.validator.Password.StringValidator.validateLen("password", password)
+	if err != nil {
+		return err
+	}
+
+	return u.validator.Password.Regex.validateRegex("password", password)
+}
+
 const insertUserQuery = `
 	INSERT INTO guard_user (
 		email,
@@ -302,71 +315,363 @@ func (u *User) DeleteContext(ctx context.Context) error {
 	return nil
 }
 
-const getAccessQuery = `
- 	SELECT EXISTS(
-		SELECT 
-			*
-		FROM guard_user_role ur 
-		JOIN guard_role_permission rp ON ur.role_id = rp.role_id
-		JOIN guard_permission p ON p.id = rp. permission_id 
-		WHERE ur.user_id = ? AND p.method = ? AND p.route = ?
-	) AS is_exist
+// PasswordAge will return the duration since the password is changed or user is registered
+func (u *User) PasswordAge() time.Duration {
+	if u.PasswordChangedAt != nil {
+		return time.Since(*u.PasswordChangedAt)
+	}
+	return time.Since(u.CreatedAt)
+}
+
+// IsEmailVerified will check user has verified the email or not
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+const verifyEmailQuery = `UPDATE guard_user SET email_verified_at = ?, updated_at = ? WHERE id = ?`
+
+// VerifyEmail function will mark the email of user as verified
+func (u *User) VerifyEmail() error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.Exec(
+		verifyEmailQuery,
+		now,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+// VerifyEmailContext function will mark the email of user as verified with specific context
+func (u *User) VerifyEmailContext(ctx context.Context) error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.ExecContext(
+		ctx,
+		verifyEmailQuery,
+		now,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+const updatePasswordQuery = `UPDATE guard_user SET password = ?, password_changed_at = ?, updated_at = ? WHERE id = ?`
+
+// UpdatePassword function will update the password of user with the encrypted password
+// The password must be encrypted before calling this function
+func (u *User) UpdatePassword(encrypted string) error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.Exec(
+		updatePasswordQuery,
+		encrypted,
+		now,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.SetEncryptedPassword(encrypted)
+	u.PasswordChangedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+// UpdatePasswordContext function will update the password of user with the encrypted password and specific context
+// The password must be encrypted before calling this function
+func (u *User) UpdatePasswordContext(ctx context.Context, encrypted string) error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.ExecContext(
+		ctx,
+		updatePasswordQuery,
+		encrypted,
+		now,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.SetEncryptedPassword(encrypted)
+	u.PasswordChangedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+const rehashPasswordQuery = `UPDATE guard_user SET password = ?, updated_at = ? WHERE id = ?`
+
+// RehashPassword function will replace the password hash of user with the new hash of the same password
+// the password changed time isn't updated, so it doesn't reset the password age.
+// The password must be encrypted before calling this function
+func (u *User) RehashPassword(encrypted string) error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.Exec(
+		rehashPasswordQuery,
+		encrypted,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.SetEncryptedPassword(encrypted)
+	u.UpdatedAt = now
+	return nil
+}
+
+// RehashPasswordContext function will replace the password hash of user with the new hash of the same password and specific context
+// the password changed time isn't updated, so it doesn't reset the password age.
+// The password must be encrypted before calling this function
+func (u *User) RehashPasswordContext(ctx context.Context, encrypted string) error {
+	if u.DBContract == nil {
+		return ErrNoSchema
+	}
+
+	if u.ID <= 0 {
+		return ErrInvalidID
+	}
+
+	now := time.Now()
+	_, err := u.DBContract.ExecContext(
+		ctx,
+		rehashPasswordQuery,
+		encrypted,
+		now,
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+	u.SetEncryptedPassword(encrypted)
+	u.UpdatedAt = now
+	return nil
+}
+
+// userRolesCTE will resolve all roles owned by user, including the roles of user's groups and their parent groups,
+// and the child roles that inherited through role hierarchy.
+// the roles assigned in domain are only included when the domain is the domain of user, see InDomain,
+// and the roles assigned temporarily are only included in their period.
+// the query params are built by userRolesParams
+const userRolesCTE = `
+	WITH RECURSIVE user_params (user_id, checked_at) AS (
+		SELECT CAST(? AS UNSIGNED), CAST(? AS DATETIME(6))
+	),
+	user_groups (group_id) AS (
+		SELECT ug.group_id FROM guard_user_group ug
+		JOIN user_params up ON ug.user_id = up.user_id
+		UNION
+		SELECT gc.parent_id FROM guard_group_child gc
+		JOIN user_groups ON gc.child_id = user_groups.group_id
+	),
+	user_roles (role_id) AS (
+		SELECT ur.role_id FROM guard_user_role ur
+		JOIN user_params up ON ur.user_id = up.user_id
+		WHERE ur.domain IN ('', ?)
+		AND (ur.valid_from IS NULL OR ur.valid_from <= up.checked_at)
+		AND (ur.valid_until IS NULL OR ur.valid_until > up.checked_at)
+		UNION
+		SELECT gr.role_id FROM guard_group_role gr
+		JOIN user_groups ON gr.group_id = user_groups.group_id
+		UNION
+		SELECT rc.child_id FROM guard_role_child rc
+		JOIN user_roles ON rc.parent_id = user_roles.role_id
+	)
 `
 
-// CanAccess function will return bool that represent this user is eligible to access the resource path or not
-// This function will check the user permission record
-func (u *User) CanAccess(method, path string) (bool, error) {
+// InDomain function will return the copy of this user that scoped to the domain (tenant)
+// the access checks of returned user include the global roles and the roles assigned in the domain,
+// while the access checks of user without domain only include the global roles
+func (u *User) InDomain(domain string) *User {
+	scoped := *u
+	scoped.domain = domain
+	return &scoped
+}
+
+// Domain function will return the domain of user that set by InDomain, empty domain means global
+func (u *User) Domain() string {
+	return u.domain
+}
+
+// userRolesParams is helper func to build the query params of userRolesCTE followed by args
+func (u *User) userRolesParams(args ...interface{}) []interface{} {
+	return append([]interface{}{u.ID, time.Now(), u.domain}, args...)
+}
+
+const getAccessQuery = userRolesCTE + `
+	SELECT DISTINCT
+		p.id,
+		p.name,
+		p.method,
+		p.route,
+		p.description,
+		p.created_at,
+		p.updated_at,
+		rp.effect
+	FROM user_roles ur 
+	JOIN guard_role_permission rp ON ur.role_id = rp.role_id
+	JOIN guard_permission p ON p.id = rp.permission_id 
+	WHERE p.method = ? OR p.method = '*'
+`
+
+// GetAccessPermission function will return the most specific permission owned by this user that matches the resource
+// the denied permission of user's roles is decided by the combining algorithm of schema, default is DenyOverrides.
+// nil will be returned if this user has no permission to access the resource
+func (u *User) GetAccessPermission(method, path string) (*Permission, error) {
 	if u.DBContract == nil {
-		return false, ErrNoSchema
+		return nil, ErrNoSchema
 	}
 
 	if !u.exist {
-		return false, UserNotFound
+		return nil, UserNotFound
 	}
 
-	var accessRecord existRecord
-	result := u.DBContract.QueryRow(getAccessQuery, u.ID, method, path)
-	err := result.Scan(&accessRecord.IsExist)
+	result, err := u.DBContract.Query(getAccessQuery, u.userRolesParams(method)...)
 	if err != nil {
-		return false, err
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
 	}
-	return accessRecord.IsExist, nil
+
+	permissions, err := scanGrantedPermissions(result, u.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	return ResolvePermission(permissions, method, path, u.combining), nil
 }
 
-// CanAccessContext function will return bool that represent this user is eligible to access the resource path or not
-// This function will check the user permission record with specific context
-func (u *User) CanAccessContext(ctx context.Context, method, path string) (bool, error) {
+// GetAccessPermissionContext function will return the most specific permission owned by this user that matches the resource
+// the denied permission of user's roles is decided by the combining algorithm of schema, default is DenyOverrides.
+// nil will be returned if this user has no permission to access the resource
+func (u *User) GetAccessPermissionContext(ctx context.Context, method, path string) (*Permission, error) {
 	if u.DBContract == nil {
-		return false, ErrNoSchema
+		return nil, ErrNoSchema
 	}
 
 	if !u.exist {
-		return false, UserNotFound
+		return nil, UserNotFound
 	}
 
-	var accessRecord existRecord
-	result := u.DBContract.QueryRowContext(ctx, getAccessQuery, u.ID, method, path)
-	err := result.Scan(&accessRecord.IsExist)
+	result, err := u.DBContract.QueryContext(ctx, getAccessQuery, u.userRolesParams(method)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	permissions, err := scanGrantedPermissions(result, u.DBContract)
+	if err != nil {
+		return nil, err
+	}
+	return ResolvePermission(permissions, method, path, u.combining), nil
+}
+
+// CanAccess function will return bool that represent this user is eligible to access the resource path or not
+// This function will check the user permission record, including the permission inherited from child roles
+// The permission route can be a pattern, see MatchRoute
+func (u *User) CanAccess(method, path string) (bool, error) {
+	permission, err := u.GetAccessPermission(method, path)
 	if err != nil {
 		return false, err
 	}
+	return permission != nil, nil
+}
 
-	return accessRecord.IsExist, nil
+// CanAccessContext function will return bool that represent this user is eligible to access the resource path or not
+// This function will check the user permission record with specific context
+// The permission route can be a pattern, see MatchRoute
+func (u *User) CanAccessContext(ctx context.Context, method, path string) (bool, error) {
+	permission, err := u.GetAccessPermissionContext(ctx, method, path)
+	if err != nil {
+		return false, err
+	}
+	return permission != nil, nil
 }
 
-const getUserPermissionQuery = `
+const getUserPermissionQuery = userRolesCTE + `
 	SELECT EXISTS(
 		SELECT 
 			*
-		FROM guard_user_role ur 
+		FROM user_roles ur 
 		JOIN guard_role_permission rp ON ur.role_id = rp.role_id
-		JOIN guard_permission p ON p.id = rp. permission_id 
-		WHERE ur.user_id = ? AND p.name = ?
+		JOIN guard_permission p ON p.id = rp.permission_id 
+		WHERE p.name = ? AND rp.effect = 'allow'
+	) AND NOT EXISTS(
+		SELECT 
+			*
+		FROM user_roles ur 
+		JOIN guard_role_permission rp ON ur.role_id = rp.role_id
+		JOIN guard_permission p ON p.id = rp.permission_id 
+		WHERE p.name = ? AND rp.effect = 'deny'
 	) AS is_exist
 `
 
+// CanAccessInDomain function will return bool that represent this user can access the resource in the domain (tenant)
+// the global roles and the roles assigned in the domain are used
+func (u *User) CanAccessInDomain(domain, method, path string) (bool, error) {
+	return u.InDomain(domain).CanAccess(method, path)
+}
+
+// CanAccessInDomainContext function will return bool that represent this user can access the resource in the domain (tenant) with specific context
+// the global roles and the roles assigned in the domain are used
+func (u *User) CanAccessInDomainContext(ctx context.Context, domain, method, path string) (bool, error) {
+	return u.InDomain(domain).CanAccessContext(ctx, method, path)
+}
+
 // HasPermission function will return bool that represent this user has permission or not
-// This function will check the user permission record by user and permissionName
+// This function will check the user permission record by user and permissionName.
+// the permission isn't owned if it's denied by any role of user, the permission name has no specificity to apply FirstApplicable
 func (u *User) HasPermission(permissionName string) (bool, error) {
 	if u.DBContract == nil {
 		return false, ErrNoSchema
@@ -377,7 +682,7 @@ func (u *User) HasPermission(permissionName string) (bool, error) {
 	}
 
 	var permissionRecord existRecord
-	result := u.DBContract.QueryRow(getUserPermissionQuery, u.ID, permissionName)
+	result := u.DBContract.QueryRow(getUserPermissionQuery, u.userRolesParams(permissionName, permissionName)...)
 	err := result.Scan(&permissionRecord.IsExist)
 	if err != nil {
 		return false, err
@@ -397,7 +702,7 @@ func (u *User) HasPermissionContext(ctx context.Context, permissionName string)
 	}
 
 	var permissionRecord existRecord
-	result := u.DBContract.QueryRowContext(ctx, getUserPermissionQuery, u.ID, permissionName)
+	result := u.DBContract.QueryRowContext(ctx, getUserPermissionQuery, u.userRolesParams(permissionName, permissionName)...)
 	err := result.Scan(&permissionRecord.IsExist)
 	if err != nil {
 		return false, err
@@ -405,18 +710,18 @@ func (u *User) HasPermissionContext(ctx context.Context, permissionName string)
 	return permissionRecord.IsExist, nil
 }
 
-const getUserRoleQuery = `
+const getUserRoleQuery = userRolesCTE + `
 	SELECT EXISTS(
 		SELECT 
 			*
-		FROM guard_user_role ur 
+		FROM user_roles ur 
 		JOIN guard_role r ON ur.role_id = r.id 
-		WHERE ur.user_id = ? AND r.name = ? 
+		WHERE r.name = ? 
 	) AS is_exist
 `
 
 // HasRole function will return bool that represent this user has specific roleName or not
-// This function will check the user role record by user and roleName
+// This function will check the user role record by user and roleName, including the child roles
 func (u *User) HasRole(roleName string) (bool, error) {
 	if u.DBContract == nil {
 		return false, ErrNoSchema
@@ -427,7 +732,7 @@ func (u *User) HasRole(roleName string) (bool, error) {
 	}
 
 	var roleRecord existRecord
-	result := u.DBContract.QueryRow(getUserRoleQuery, u.ID, roleName)
+	result := u.DBContract.QueryRow(getUserRoleQuery, u.userRolesParams(roleName)...)
 	err := result.Scan(&roleRecord.IsExist)
 	if err != nil {
 		return false, err
@@ -447,7 +752,7 @@ func (u *User) HasRoleContext(ctx context.Context, roleName string) (bool, error
 	}
 
 	var roleRecord existRecord
-	result := u.DBContract.QueryRowContext(ctx, getUserRoleQuery, u.ID, roleName)
+	result := u.DBContract.QueryRowContext(ctx, getUserRoleQuery, u.userRolesParams(roleName)...)
 	err := result.Scan(&roleRecord.IsExist)
 	if err != nil {
 		return false, err
@@ -455,20 +760,21 @@ func (u *User) HasRoleContext(ctx context.Context, roleName string) (bool, error
 	return roleRecord.IsExist, nil
 }
 
-const getUserRolesQuery = `
+const getUserRolesQuery = userRolesCTE + `
 	SELECT
 		r.id,
 		r.name,
 		r.description,
 		r.created_at,
 		r.updated_at
-	FROM guard_role r
-	JOIN guard_user_role ur ON ur.role_id = r.id 
-	WHERE ur.user_id = ?
+	FROM user_roles ur
+	JOIN guard_role r ON ur.role_id = r.id
 `
 
 // GetRoles function will return roles by this user ID
-// This function will check the user role record by this specific userID
+// This function will check the user role record by this specific userID, including the roles assigned in the domain of user,
+// the roles of user's groups and the child roles, so it returns the same roles that HasRole checks.
+// the temporary role is only returned in its period
 func (u *User) GetRoles() ([]Role, error) {
 	if u.DBContract == nil {
 		return nil, ErrNoSchema
@@ -481,7 +787,7 @@ func (u *User) GetRoles() ([]Role, error) {
 	var roles []Role
 
 	roles = make([]Role, 0)
-	result, err := u.DBContract.Query(getUserRolesQuery, u.ID)
+	result, err := u.DBContract.Query(getUserRolesQuery, u.userRolesParams()...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return roles, nil
@@ -489,24 +795,7 @@ func (u *User) GetRoles() ([]Role, error) {
 		return nil, err
 	}
 
-	var role Role
-	role.DBContract = u.DBContract
-	for result.Next() {
-		err = result.Scan(
-			&role.ID,
-			&role.Name,
-			&role.Description,
-			&role.Description,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-		)
-		if err == nil {
-			role.exist = true
-			roles = append(roles, role)
-		}
-		return nil, err
-	}
-	return roles, nil
+	return scanRoles(result, u.DBContract)
 }
 
 // GetRolesContext function will return roles by this user ID and context
@@ -523,7 +812,7 @@ func (u *User) GetRolesContext(ctx context.Context) ([]Role, error) {
 	var roles []Role
 
 	roles = make([]Role, 0)
-	result, err := u.DBContract.QueryContext(ctx, getUserRolesQuery, u.ID)
+	result, err := u.DBContract.QueryContext(ctx, getUserRolesQuery, u.userRolesParams()...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return roles, nil
@@ -531,19 +820,169 @@ func (u *User) GetRolesContext(ctx context.Context) ([]Role, error) {
 		return nil, err
 	}
 
-	var role Role
-	for result.Next() {
-		err = result.Scan(&role)
-		if err == nil {
-			role.exist = true
-			roles = append(roles, role)
+	return scanRoles(result, u.DBContract)
+}
+
+const getUserGroupsQuery = `
+	SELECT
+		g.id,
+		g.name,
+		g.description,
+		g.created_at,
+		g.updated_at
+	FROM guard_group g
+	JOIN guard_user_group ug ON ug.group_id = g.id
+	WHERE ug.user_id = ?
+`
+
+// GetGroups function will return the groups that this user is a direct member of
+// the parent groups aren't included
+func (u *User) GetGroups() ([]Group, error) {
+	if u.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !u.exist {
+		return nil, UserNotFound
+	}
+
+	result, err := u.DBContract.Query(getUserGroupsQuery, u.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Group, 0), nil
 		}
+		return nil, err
 	}
-	return roles, nil
+	return scanGroups(result, u.DBContract)
 }
 
-const getUserPermissionsQuery = `
-	SELECT
+// GetGroupsContext function will return the groups that this user is a direct member of with specific context
+// the parent groups aren't included
+func (u *User) GetGroupsContext(ctx context.Context) ([]Group, error) {
+	if u.DBContract == nil {
+		return nil, ErrNoSchema
+	}
+
+	if !u.exist {
+		return nil, UserNotFound
+	}
+
+	result, err := u.DBContract.QueryContext(ctx, getUserGroupsQuery, u.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return make([]Group, 0), nil
+		}
+		return nil, err
+	}
+	return scanGroups(result, u.DBContract)
+}
+
+const getUserGroupQuery = userRolesCTE + `
+	SELECT EXISTS(
+		SELECT
+			*
+		FROM user_groups ug
+		JOIN guard_group g ON ug.group_id = g.id
+		WHERE g.name = ?
+	) AS is_exist
+`
+
+// HasGroup function will return bool that represent this user is member of specific groupName or not
+// the member of child group is also member of its parent groups
+func (u *User) HasGroup(groupName string) (bool, error) {
+	if u.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	if !u.exist {
+		return false, UserNotFound
+	}
+
+	var groupRecord existRecord
+	result := u.DBContract.QueryRow(getUserGroupQuery, u.userRolesParams(groupName)...)
+	err := result.Scan(&groupRecord.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return groupRecord.IsExist, nil
+}
+
+// HasGroupContext function will return bool that represent this user is member of specific groupName or not with specific context
+// the member of child group is also member of its parent groups
+func (u *User) HasGroupContext(ctx context.Context, groupName string) (bool, error) {
+	if u.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	if !u.exist {
+		return false, UserNotFound
+	}
+
+	var groupRecord existRecord
+	result := u.DBContract.QueryRowContext(ctx, getUserGroupQuery, u.userRolesParams(groupName)...)
+	err := result.Scan(&groupRecord.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return groupRecord.IsExist, nil
+}
+
+const canOnObjectQuery = userRolesCTE + `
+	SELECT EXISTS(
+		SELECT
+			*
+		FROM guard_resource_acl a
+		WHERE a.resource_type = ? AND a.resource_id = ? AND a.action IN (?, '*')
+		AND (
+			(a.principal_type = 'user' AND a.principal_id = ?)
+			OR (a.principal_type = 'role' AND a.principal_id IN (SELECT role_id FROM user_roles))
+			OR (a.principal_type = 'group' AND a.principal_id IN (SELECT group_id FROM user_groups))
+		)
+	) AS is_exist
+`
+
+// CanOnObject function will return bool that represent this user can do the action on the object or not
+// the action is granted to this user directly, or through the roles and groups of user, see ResourceACL
+func (u *User) CanOnObject(action, resourceType, resourceID string) (bool, error) {
+	if u.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	if !u.exist {
+		return false, UserNotFound
+	}
+
+	var aclRecord existRecord
+	result := u.DBContract.QueryRow(canOnObjectQuery, u.userRolesParams(resourceType, resourceID, action, u.ID)...)
+	err := result.Scan(&aclRecord.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return aclRecord.IsExist, nil
+}
+
+// CanOnObjectContext function will return bool that represent this user can do the action on the object or not with specific context
+// the action is granted to this user directly, or through the roles and groups of user, see ResourceACL
+func (u *User) CanOnObjectContext(ctx context.Context, action, resourceType, resourceID string) (bool, error) {
+	if u.DBContract == nil {
+		return false, ErrNoSchema
+	}
+
+	if !u.exist {
+		return false, UserNotFound
+	}
+
+	var aclRecord existRecord
+	result := u.DBContract.QueryRowContext(ctx, canOnObjectQuery, u.userRolesParams(resourceType, resourceID, action, u.ID)...)
+	err := result.Scan(&aclRecord.IsExist)
+	if err != nil {
+		return false, err
+	}
+	return aclRecord.IsExist, nil
+}
+
+const getUserPermissionsQuery = userRolesCTE + `
+	SELECT DISTINCT
 		p.id,
 		p.name,
 		p.method,
@@ -553,12 +992,17 @@ const getUserPermissionsQuery = `
 		p.updated_at
 	FROM guard_permission p 
 	JOIN guard_role_permission pr ON pr.permission_id = p.id
-	JOIN guard_user_role ru ON ru.role_id = pr.role_id
-	WHERE ru.user_id = ?
+	JOIN user_roles ru ON ru.role_id = pr.role_id
+	WHERE pr.effect = 'allow' AND p.id NOT IN (
+		SELECT dp.permission_id FROM guard_role_permission dp
+		JOIN user_roles du ON du.role_id = dp.role_id
+		WHERE dp.effect = 'deny'
+	)
 `
 
 // GetPermissions function will return permissions by this user ID
-// This function will check the user permission record by specific userID
+// This function will check the user permission record by specific userID, including the permission inherited from child roles.
+// the permission that denied by any role of user isn't returned
 func (u *User) GetPermissions() ([]Permission, error) {
 	if u.DBContract == nil {
 		return nil, ErrNoSchema
@@ -569,7 +1013,7 @@ func (u *User) GetPermissions() ([]Permission, error) {
 	}
 
 	permissions := make([]Permission, 0)
-	result, err := u.DBContract.Query(getUserPermissionsQuery, u.ID)
+	result, err := u.DBContract.Query(getUserPermissionsQuery, u.userRolesParams()...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return permissions, nil
@@ -577,6 +1021,8 @@ func (u *User) GetPermissions() ([]Permission, error) {
 		return nil, err
 	}
 
+	defer result.Close()
+
 	var permission Permission
 	permission.DBContract = u.DBContract
 	for result.Next() {
@@ -586,15 +1032,14 @@ func (u *User) GetPermissions() ([]Permission, error) {
 			&permission.Method,
 			&permission.Route,
 			&permission.Description,
-			&permission.Description,
 			&permission.CreatedAt,
 			&permission.UpdatedAt,
 		)
-		if err == nil {
-			permission.exist = true
-			permissions = append(permissions, permission)
+		if err != nil {
+			return nil, err
 		}
-		return nil, err
+		permission.exist = true
+		permissions = append(permissions, permission)
 	}
 	return permissions, nil
 }
@@ -611,7 +1056,7 @@ func (u *User) GetPermissionsContext(ctx context.Context) ([]Permission, error)
 	}
 
 	permissions := make([]Permission, 0)
-	result, err := u.DBContract.QueryContext(ctx, getUserPermissionsQuery, u.ID)
+	result, err := u.DBContract.QueryContext(ctx, getUserPermissionsQuery, u.userRolesParams()...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return permissions, nil
@@ -619,6 +1064,8 @@ func (u *User) GetPermissionsContext(ctx context.Context) ([]Permission, error)
 		return nil, err
 	}
 
+	defer result.Close()
+
 	var permission Permission
 	permission.DBContract = u.DBContract
 	for result.Next() {
@@ -628,15 +1075,14 @@ func (u *User) GetPermissionsContext(ctx context.Context) ([]Permission, error)
 			&permission.Method,
 			&permission.Route,
 			&permission.Description,
-			&permission.Description,
 			&permission.CreatedAt,
 			&permission.UpdatedAt,
 		)
-		if err == nil {
-			permission.exist = true
-			permissions = append(permissions, permission)
+		if err != nil {
+			return nil, err
 		}
-		return nil, err
+		permission.exist = true
+		permissions = append(permissions, permission)
 	}
 	return permissions, nil
 }
@@ -650,6 +1096,8 @@ const fetchUserByUsernameOrEmail = `
 		username, 
 		password, 
 		active,
+		email_verified_at,
+		password_changed_at,
 		created_at,
 		updated_at
 	FROM guard_user WHERE email = ? OR username = ? LIMIT 1
@@ -670,6 +1118,8 @@ func (u *User) FindUserByUsernameOrEmail(params string) (*User, error) {
 		&user.Username,
 		&user.Password,
 		&user.Active,
+		&user.EmailVerifiedAt,
+		&user.PasswordChangedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -699,6 +1149,8 @@ func (u *User) FindUserByUsernameOrEmailContext(ctx context.Context, params stri
 		&user.Username,
 		&user.Password,
 		&user.Active,
+		&user.EmailVerifiedAt,
+		&user.PasswordChangedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -720,6 +1172,8 @@ const fetchDynamicUserParams = `
 			username, 
 			password, 
 			active,
+			email_verified_at,
+			password_changed_at,
 			created_at,
 			updated_at
 		FROM guard_user WHERE 
@@ -758,6 +1212,8 @@ func (u *User) FindUser(params map[string]interface{}) (*User, error) {
 		&user.Username,
 		&user.Password,
 		&user.Active,
+		&user.EmailVerifiedAt,
+		&user.PasswordChangedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -805,6 +1261,8 @@ func (u *User) FindUserContext(ctx context.Context, params map[string]interface{
 		&user.Username,
 		&user.Password,
 		&user.Active,
+		&user.EmailVerifiedAt,
+		&user.PasswordChangedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -818,3 +1276,31 @@ func (u *User) FindUserContext(ctx context.Context, params map[string]interface{
 	user.exist = true
 	return user, nil
 }
+
+// scanUsers is helper func to scan user rows into user collection
+func scanUsers(rows *sql.Rows, dbContract DbContract) ([]User, error) {
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Username,
+			&user.Password,
+			&user.Active,
+			&user.EmailVerifiedAt,
+			&user.PasswordChangedAt,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.DBContract = dbContract
+		user.exist = true
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
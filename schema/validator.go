@@ -13,6 +13,7 @@ type Validator struct {
 	Rule       *RuleValidator       `json:"rule"`
 	Role       *RoleValidator       `json:"role"`
 	Permission *PermissionValidator `json:"permission"`
+	Group      *GroupValidator      `json:"group"`
 }
 
 // Initialize function will init nil config for validator
@@ -33,6 +34,10 @@ func (v *Validator) Initialize() {
 		v.Permission = &PermissionValidator{}
 	}
 	v.Permission.FillEmptyValidator()
+	if v.Group == nil {
+		v.Group = &GroupValidator{}
+	}
+	v.Group.FillEmptyValidator()
 }
 
 var (
@@ -42,6 +47,10 @@ var (
 	defaultEmailRegex   = `^(([^<>()\[\]\\.,;:\s@"]+(\.[^<>()\[\]\\.,;:\s@"]+)*)|(".+"))@((\[[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}])|(([a-zA-Z\-0-9]+\.)+[a-zA-Z]{2,}))$`
 	defaultNameRegex    = "^[a-zA-Z0-9_]*$"
 	defaultErrNameRegex = "%s only accept lowerCase, upperCase letter, digit, and underscore"
+
+	// defaultPasswordRegex accepts any character except control characters, so the special characters can be used
+	defaultPasswordRegex    = `^[^\x00-\x1f\x7f]*$`
+	defaultErrPasswordRegex = "{attr} must not contain control characters"
 )
 
 // RegexValidator will validate used regex  in some attribute
@@ -131,6 +140,9 @@ func (u *UserValidator) FillEmptyValidator() {
 	}
 
 	// password validator
+	if u.Password == nil {
+		u.Password = &StringRegexValidator{}
+	}
 	if u.Password.StringValidator == nil {
 		u.Password.StringValidator = setDefaultStringValidator()
 	}
@@ -142,7 +154,10 @@ func (u *UserValidator) FillEmptyValidator() {
 	}
 
 	if u.Password.Regex == nil {
-		u.Password.Regex = setDefaultRegexValidator()
+		u.Password.Regex = &RegexValidator{
+			Regex:       defaultPasswordRegex,
+			RegexErrMsg: defaultErrPasswordRegex,
+		}
 	}
 }
 
@@ -207,3 +222,24 @@ func (p *PermissionValidator) FillEmptyValidator() {
 		p.Name.Max = &defaultMaxLengthString
 	}
 }
+
+// GroupValidator contains constraint for validate group entity
+type GroupValidator struct {
+	Name *StringValidator `json:"name"`
+}
+
+// FillEmptyValidator will fill all nil constraints to prevent NilPointer
+func (g *GroupValidator) FillEmptyValidator() {
+	if g.Name == nil {
+		g.Name = setDefaultStringValidator()
+		return
+	}
+
+	if g.Name.Min == nil {
+		g.Name.Min = &defaultMinLengthString
+	}
+
+	if g.Name.Max == nil {
+		g.Name.Max = &defaultMaxLengthString
+	}
+}